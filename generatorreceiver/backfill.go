@@ -0,0 +1,89 @@
+package generatorreceiver
+
+import (
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/lightstep/telemetry-generator/generatorreceiver/internal/cron"
+)
+
+// runBackfill drives the generators on the simulated clock from the start to the end of the
+// backfill, generating each one's telemetry as fast as the consumers accept it. Cron schedules
+// are evaluated against the simulated clock, from the start of the backfill.
+func (g *generatorReceiver) runBackfill(done chan struct{}, restoreClock func()) {
+	defer restoreClock()
+
+	g.mu.Lock()
+	generators := g.backfillGenerators()
+	simulated, end, b := g.backfillClock, g.backfillEnd, g.batcher
+	g.mu.Unlock()
+
+	start := simulated.Now()
+	g.logger.Info("backfilling", zap.Time("start", start), zap.Time("end", end), zap.Int("generators", len(generators)))
+
+	next := make([]time.Time, len(generators))
+	for i := range next {
+		next[i] = generators[i].firstTick(start)
+	}
+	now := start
+	for {
+		select {
+		case <-done:
+			g.logger.Info("backfill stopped", zap.Time("at", now))
+			return
+		default:
+		}
+
+		// the generator that is due first generates next, ties go to the first one.
+		i := -1
+		for j := range next {
+			if i < 0 || next[j].Before(next[i]) {
+				i = j
+			}
+		}
+		if i < 0 || !next[i].Before(end) {
+			break
+		}
+
+		simulated.Set(next[i])
+		cron.RunBetween(now, next[i])
+		now = next[i]
+		generators[i].tick()
+		next[i] = generators[i].nextTick(now)
+	}
+	if b != nil {
+		b.flush()
+	}
+	g.logger.Info("backfill finished", zap.Time("start", start), zap.Time("end", end))
+}
+
+// backfillGenerators returns the trace generators in the order of their root routes, then the
+// metric generators by service, so that a seeded backfill always generates the same telemetry.
+func (g *generatorReceiver) backfillGenerators() []*runningGenerator {
+	var generators []*runningGenerator
+	for _, key := range rootRouteFingerprints(g.topoFile, g.serviceFingerprints) {
+		if r, ok := g.traceGenerators[key.fingerprint]; ok {
+			generators = append(generators, r)
+		}
+	}
+
+	keys := make([]string, 0, len(g.metricGenerators))
+	for k := range g.metricGenerators {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		generators = append(generators, g.metricGenerators[k]...)
+	}
+
+	// generators that would never advance the clock would never let the backfill finish.
+	valid := generators[:0]
+	for _, r := range generators {
+		if r.period > 0 {
+			valid = append(valid, r)
+		}
+	}
+	return valid
+}
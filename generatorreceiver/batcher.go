@@ -0,0 +1,319 @@
+package generatorreceiver
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.uber.org/zap"
+)
+
+const (
+	DefaultBatchTimeout = 200 * time.Millisecond
+	DefaultBatchWorkers = 4
+)
+
+// batcher groups the spans and metrics of all generators by resource and scope into batches, see
+// BatchConfig. Batches are consumed by a pool of workers; when the consumers fall behind and the
+// queue fills up, generators wait for room in the queue, which batchStats keeps track of.
+type batcher struct {
+	cfg            BatchConfig
+	logger         *zap.Logger
+	telemetry      *receiverTelemetry
+	traceConsumer  consumer.Traces
+	metricConsumer consumer.Metrics
+
+	mu      sync.Mutex
+	traces  *traceBatch
+	metrics *metricBatch
+
+	// closed is set once the queue is closed, under closeMu so that no batch is queued meanwhile.
+	closeMu sync.RWMutex
+	closed  bool
+	queue   chan func() error
+	done    chan struct{}
+	wg      sync.WaitGroup
+	stats   batchStats
+}
+
+// batchStats are the counters of a batcher, reported by the API.
+type batchStats struct {
+	Batches        int64   `json:"batches"`
+	Spans          int64   `json:"spans"`
+	DataPoints     int64   `json:"dataPoints"`
+	ConsumeErrors  int64   `json:"consumeErrors"`
+	BusyWorkers    int64   `json:"busyWorkers"`
+	Blocked        int64   `json:"blocked"` // times a generator waited for room in the queue
+	BlockedNanos   int64   `json:"-"`
+	BlockedSeconds float64 `json:"blockedSeconds"` // total time generators waited for room in the queue
+	Queued         int     `json:"queued"`
+	QueueSize      int     `json:"queueSize"`
+	Workers        int     `json:"workers"`
+}
+
+func newBatcher(cfg BatchConfig, logger *zap.Logger, telemetry *receiverTelemetry, traceConsumer consumer.Traces, metricConsumer consumer.Metrics) *batcher {
+	return &batcher{
+		cfg:            cfg,
+		logger:         logger,
+		telemetry:      telemetry,
+		traceConsumer:  traceConsumer,
+		metricConsumer: metricConsumer,
+		traces:         newTraceBatch(),
+		metrics:        newMetricBatch(),
+		queue:          make(chan func() error, cfg.GetQueueSize()),
+		done:           make(chan struct{}),
+	}
+}
+
+// start starts the workers, and queues partial batches every timeout.
+func (b *batcher) start() {
+	for i := 0; i < b.cfg.GetWorkers(); i++ {
+		b.wg.Add(1)
+		go b.work()
+	}
+	go func() {
+		ticker := time.NewTicker(b.cfg.GetTimeout())
+		defer ticker.Stop()
+		for {
+			select {
+			case <-b.done:
+				return
+			case <-ticker.C:
+				b.flush()
+			}
+		}
+	}()
+}
+
+// shutdown queues what is left and waits until all batches are consumed. Telemetry added
+// afterwards is dropped.
+func (b *batcher) shutdown() {
+	close(b.done)
+	b.flush()
+	b.closeMu.Lock()
+	b.closed = true
+	close(b.queue)
+	b.closeMu.Unlock()
+	b.wg.Wait()
+}
+
+func (b *batcher) work() {
+	defer b.wg.Done()
+	for consume := range b.queue {
+		atomic.AddInt64(&b.stats.BusyWorkers, 1)
+		err := consume()
+		atomic.AddInt64(&b.stats.BusyWorkers, -1)
+		atomic.AddInt64(&b.stats.Batches, 1)
+		if err != nil {
+			atomic.AddInt64(&b.stats.ConsumeErrors, 1)
+			b.telemetry.addConsumeError()
+			b.logger.Error("consume error", zap.Error(err))
+		}
+	}
+}
+
+func (b *batcher) addTraces(td ptrace.Traces) {
+	b.mu.Lock()
+	b.traces.add(td)
+	var full *traceBatch
+	if b.traces.count >= b.cfg.Size {
+		full, b.traces = b.traces, newTraceBatch()
+	}
+	b.mu.Unlock()
+
+	if full != nil {
+		b.enqueueTraces(full)
+	}
+}
+
+func (b *batcher) addMetrics(md pmetric.Metrics) {
+	b.mu.Lock()
+	b.metrics.add(md)
+	var full *metricBatch
+	if b.metrics.count >= b.cfg.Size {
+		full, b.metrics = b.metrics, newMetricBatch()
+	}
+	b.mu.Unlock()
+
+	if full != nil {
+		b.enqueueMetrics(full)
+	}
+}
+
+// flush queues the current batches, however full they are.
+func (b *batcher) flush() {
+	b.mu.Lock()
+	traces, metrics := b.traces, b.metrics
+	b.traces, b.metrics = newTraceBatch(), newMetricBatch()
+	b.mu.Unlock()
+
+	if traces.count > 0 {
+		b.enqueueTraces(traces)
+	}
+	if metrics.count > 0 {
+		b.enqueueMetrics(metrics)
+	}
+}
+
+func (b *batcher) enqueueTraces(batch *traceBatch) {
+	atomic.AddInt64(&b.stats.Spans, int64(batch.count))
+	b.enqueue(func() error {
+		return b.traceConsumer.ConsumeTraces(context.Background(), batch.td)
+	})
+}
+
+func (b *batcher) enqueueMetrics(batch *metricBatch) {
+	atomic.AddInt64(&b.stats.DataPoints, int64(batch.count))
+	b.enqueue(func() error {
+		return b.metricConsumer.ConsumeMetrics(context.Background(), batch.md)
+	})
+}
+
+// enqueue queues consume, waiting for room in the queue if it is full.
+func (b *batcher) enqueue(consume func() error) {
+	b.closeMu.RLock()
+	defer b.closeMu.RUnlock()
+	if b.closed {
+		return
+	}
+
+	select {
+	case b.queue <- consume:
+		return
+	default:
+	}
+	start := time.Now()
+	b.queue <- consume
+	atomic.AddInt64(&b.stats.Blocked, 1)
+	atomic.AddInt64(&b.stats.BlockedNanos, int64(time.Since(start)))
+}
+
+// snapshot returns the current stats of the batcher.
+func (b *batcher) snapshot() batchStats {
+	blockedNanos := atomic.LoadInt64(&b.stats.BlockedNanos)
+	return batchStats{
+		Batches:        atomic.LoadInt64(&b.stats.Batches),
+		Spans:          atomic.LoadInt64(&b.stats.Spans),
+		DataPoints:     atomic.LoadInt64(&b.stats.DataPoints),
+		ConsumeErrors:  atomic.LoadInt64(&b.stats.ConsumeErrors),
+		BusyWorkers:    atomic.LoadInt64(&b.stats.BusyWorkers),
+		Blocked:        atomic.LoadInt64(&b.stats.Blocked),
+		BlockedNanos:   blockedNanos,
+		BlockedSeconds: time.Duration(blockedNanos).Seconds(),
+		Queued:         len(b.queue),
+		QueueSize:      cap(b.queue),
+		Workers:        b.cfg.GetWorkers(),
+	}
+}
+
+// traceBatch merges traces into a single ptrace.Traces with one ResourceSpans per resource, and
+// one ScopeSpans per scope within it.
+type traceBatch struct {
+	td        ptrace.Traces
+	resources map[string]ptrace.ResourceSpans
+	scopes    map[string]ptrace.SpanSlice
+	count     int
+}
+
+func newTraceBatch() *traceBatch {
+	return &traceBatch{
+		td:        ptrace.NewTraces(),
+		resources: make(map[string]ptrace.ResourceSpans),
+		scopes:    make(map[string]ptrace.SpanSlice),
+	}
+}
+
+func (b *traceBatch) add(td ptrace.Traces) {
+	for i := 0; i < td.ResourceSpans().Len(); i++ {
+		rs := td.ResourceSpans().At(i)
+		resourceKey := attributesKey(rs.Resource().Attributes())
+		for j := 0; j < rs.ScopeSpans().Len(); j++ {
+			ss := rs.ScopeSpans().At(j)
+			key := resourceKey + "|" + scopeKey(ss.Scope())
+			spans, ok := b.scopes[key]
+			if !ok {
+				dest, ok := b.resources[resourceKey]
+				if !ok {
+					dest = b.td.ResourceSpans().AppendEmpty()
+					rs.Resource().CopyTo(dest.Resource())
+					dest.SetSchemaUrl(rs.SchemaUrl())
+					b.resources[resourceKey] = dest
+				}
+				destScope := dest.ScopeSpans().AppendEmpty()
+				ss.Scope().CopyTo(destScope.Scope())
+				destScope.SetSchemaUrl(ss.SchemaUrl())
+				spans = destScope.Spans()
+				b.scopes[key] = spans
+			}
+			b.count += ss.Spans().Len()
+			ss.Spans().MoveAndAppendTo(spans)
+		}
+	}
+}
+
+// metricBatch merges metrics like traceBatch merges traces, counting data points.
+type metricBatch struct {
+	md        pmetric.Metrics
+	resources map[string]pmetric.ResourceMetrics
+	scopes    map[string]pmetric.MetricSlice
+	count     int
+}
+
+func newMetricBatch() *metricBatch {
+	return &metricBatch{
+		md:        pmetric.NewMetrics(),
+		resources: make(map[string]pmetric.ResourceMetrics),
+		scopes:    make(map[string]pmetric.MetricSlice),
+	}
+}
+
+func (b *metricBatch) add(md pmetric.Metrics) {
+	b.count += md.DataPointCount()
+	for i := 0; i < md.ResourceMetrics().Len(); i++ {
+		rm := md.ResourceMetrics().At(i)
+		resourceKey := attributesKey(rm.Resource().Attributes())
+		for j := 0; j < rm.ScopeMetrics().Len(); j++ {
+			sm := rm.ScopeMetrics().At(j)
+			key := resourceKey + "|" + scopeKey(sm.Scope())
+			metrics, ok := b.scopes[key]
+			if !ok {
+				dest, ok := b.resources[resourceKey]
+				if !ok {
+					dest = b.md.ResourceMetrics().AppendEmpty()
+					rm.Resource().CopyTo(dest.Resource())
+					dest.SetSchemaUrl(rm.SchemaUrl())
+					b.resources[resourceKey] = dest
+				}
+				destScope := dest.ScopeMetrics().AppendEmpty()
+				sm.Scope().CopyTo(destScope.Scope())
+				destScope.SetSchemaUrl(sm.SchemaUrl())
+				metrics = destScope.Metrics()
+				b.scopes[key] = metrics
+			}
+			sm.Metrics().MoveAndAppendTo(metrics)
+		}
+	}
+}
+
+// attributesKey identifies a set of attributes, whatever their order.
+func attributesKey(attrs pcommon.Map) string {
+	pairs := make([]string, 0, attrs.Len())
+	attrs.Range(func(k string, v pcommon.Value) bool {
+		pairs = append(pairs, fmt.Sprintf("%q=%s:%q", k, v.Type(), v.AsString()))
+		return true
+	})
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func scopeKey(scope pcommon.InstrumentationScope) string {
+	return fmt.Sprintf("%q %q %s", scope.Name(), scope.Version(), attributesKey(scope.Attributes()))
+}
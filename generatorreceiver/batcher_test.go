@@ -0,0 +1,121 @@
+package generatorreceiver
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/consumer/consumertest"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.uber.org/zap"
+)
+
+func testTraces(service string, spans int) ptrace.Traces {
+	td := ptrace.NewTraces()
+	rs := td.ResourceSpans().AppendEmpty()
+	rs.Resource().Attributes().PutStr("service.name", service)
+	ss := rs.ScopeSpans().AppendEmpty()
+	ss.Scope().SetName("telemetry-generator")
+	for i := 0; i < spans; i++ {
+		ss.Spans().AppendEmpty().SetName("span")
+	}
+	return td
+}
+
+func TestBatcher_GroupsByResource(t *testing.T) {
+	traces := new(consumertest.TracesSink)
+	metrics := new(consumertest.MetricsSink)
+	b := newBatcher(BatchConfig{Size: 10, Timeout: time.Hour}, zap.NewNop(), &receiverTelemetry{}, traces, metrics)
+	b.start()
+
+	b.addTraces(testTraces("frontend", 2))
+	b.addTraces(testTraces("backend", 2))
+	b.addTraces(testTraces("frontend", 2))
+	for i := 0; i < 3; i++ {
+		md := pmetric.NewMetrics()
+		rm := md.ResourceMetrics().AppendEmpty()
+		rm.Resource().Attributes().PutStr("service.name", "frontend")
+		rm.ScopeMetrics().AppendEmpty().Metrics().AppendEmpty().SetEmptyGauge().DataPoints().AppendEmpty().SetIntValue(1)
+		b.addMetrics(md)
+	}
+	require.Zero(t, traces.SpanCount(), "the batch is not full yet")
+
+	// filling the batch queues it
+	b.addTraces(testTraces("backend", 4))
+	require.Eventually(t, func() bool { return traces.SpanCount() == 10 }, time.Second, time.Millisecond)
+	require.Len(t, traces.AllTraces(), 1)
+	td := traces.AllTraces()[0]
+	require.Equal(t, 2, td.ResourceSpans().Len())
+	require.Equal(t, 4, td.ResourceSpans().At(0).ScopeSpans().At(0).Spans().Len())
+	require.Equal(t, 1, td.ResourceSpans().At(0).ScopeSpans().Len())
+
+	// shutting down consumes what is left
+	b.shutdown()
+	require.Len(t, metrics.AllMetrics(), 1)
+	require.Equal(t, 3, metrics.AllMetrics()[0].ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().Len())
+	stats := b.snapshot()
+	require.Equal(t, int64(2), stats.Batches)
+	require.Equal(t, int64(10), stats.Spans)
+	require.Equal(t, int64(3), stats.DataPoints)
+
+	b.addTraces(testTraces("frontend", 10))
+	require.Equal(t, 10, traces.SpanCount(), "the batcher is shut down")
+}
+
+func TestBatcher_Backpressure(t *testing.T) {
+	release := make(chan struct{})
+	slow, err := consumer.NewTraces(func(context.Context, ptrace.Traces) error {
+		<-release
+		return nil
+	})
+	require.NoError(t, err)
+	b := newBatcher(BatchConfig{Size: 1, Timeout: time.Hour, Workers: 1, QueueSize: 1}, zap.NewNop(), &receiverTelemetry{}, slow, nil)
+	b.start()
+
+	// one batch is being consumed and one is queued, so the third one waits for room in the queue
+	b.addTraces(testTraces("frontend", 1))
+	require.Eventually(t, func() bool { return b.snapshot().BusyWorkers == 1 }, time.Second, time.Millisecond)
+	b.addTraces(testTraces("frontend", 1))
+	added := make(chan struct{})
+	go func() {
+		b.addTraces(testTraces("frontend", 1))
+		close(added)
+	}()
+	select {
+	case <-added:
+		t.Fatal("the generator should wait while the queue is full")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	<-added
+	b.shutdown()
+	stats := b.snapshot()
+	require.Equal(t, int64(3), stats.Batches)
+	require.Equal(t, int64(1), stats.Blocked)
+	require.Greater(t, stats.BlockedSeconds, 0.0)
+}
+
+func TestGeneratorReceiver_Batch(t *testing.T) {
+	topoPath := filepath.Join(t.TempDir(), "topo.yaml")
+	require.NoError(t, os.WriteFile(topoPath, []byte(backfillTestTopo), 0600))
+	backfill := BackfillConfig{Start: "2023-01-01T00:30:00Z", End: "2023-01-01T02:30:00Z"}
+	g := newTestReceiver(t, &Config{Path: topoPath, Backfill: backfill, Batch: BatchConfig{Size: 1000, Timeout: time.Hour}})
+	traces := g.traceConsumer.(*consumertest.TracesSink)
+	metrics := g.metricConsumer.(*consumertest.MetricsSink)
+
+	// the same telemetry as without batching, in batches of 1000 spans
+	require.Eventually(t, func() bool {
+		return traces.SpanCount() == 7200 && metrics.DataPointCount() == 240
+	}, 30*time.Second, 10*time.Millisecond)
+	require.Len(t, traces.AllTraces(), 8)
+	require.Equal(t, 1, traces.AllTraces()[0].ResourceSpans().Len())
+
+	require.NoError(t, g.Shutdown(context.Background()))
+	require.Nil(t, g.batcher)
+}
@@ -0,0 +1,116 @@
+// Command telemetry-render renders a topology to OTLP files without running a collector, e.g. to
+// generate test fixtures:
+//
+//	telemetry-render -topo examples/hipster_shop.yaml -seed 1 -traces 100 -out traces.pb
+//	telemetry-render -topo examples/hipster_shop.yaml -seed 1 -metrics 1h -out metrics.json
+//
+// Files are written as OTLP protobuf (ExportTraceServiceRequest/ExportMetricsServiceRequest
+// messages) or, with -format json or a .json output file, as OTLP JSON.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.uber.org/zap"
+
+	"github.com/lightstep/telemetry-generator/generatorreceiver"
+)
+
+const (
+	protoFormat = "proto"
+	jsonFormat  = "json"
+)
+
+func main() {
+	topoPath := flag.String("topo", "", "topology file to render, - reads it from stdin")
+	traces := flag.Int("traces", 0, "number of traces to render")
+	metrics := flag.Duration("metrics", 0, "time window of metrics to render, e.g. 1h")
+	start := flag.String("start", "", "RFC 3339 start time of the rendered telemetry (default now)")
+	seed := flag.Int64("seed", 0, "random seed, the same topology and seed render the same telemetry (default random)")
+	out := flag.String("out", "", "output file")
+	format := flag.String("format", "", "output format, proto or json (default json for .json files, otherwise proto)")
+	verbose := flag.Bool("v", false, "log generation")
+	flag.Parse()
+
+	err := run(*topoPath, *traces, *metrics, *start, *seed, *out, *format, *verbose)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "telemetry-render: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(topoPath string, traces int, metrics time.Duration, start string, seed int64, out string, format string, verbose bool) error {
+	if topoPath == "" || out == "" {
+		return fmt.Errorf("-topo and -out are required")
+	}
+	if (traces > 0) == (metrics > 0) {
+		return fmt.Errorf("exactly one of -traces and -metrics is required")
+	}
+	if format == "" {
+		format = protoFormat
+		if strings.HasSuffix(strings.ToLower(out), ".json") {
+			format = jsonFormat
+		}
+	}
+	if format != protoFormat && format != jsonFormat {
+		return fmt.Errorf("unknown format %s", format)
+	}
+
+	options := generatorreceiver.RenderOptions{Traces: traces, Window: metrics, Start: time.Now(), Seed: seed}
+	if start != "" {
+		var err error
+		options.Start, err = time.Parse(time.RFC3339, start)
+		if err != nil {
+			return fmt.Errorf("invalid -start: %w", err)
+		}
+	}
+	if seed == 0 {
+		options.Seed = time.Now().UnixNano()
+	}
+	logger := zap.NewNop()
+	if verbose {
+		logger, _ = zap.NewDevelopment()
+	}
+
+	var bytes []byte
+	if traces > 0 {
+		td, err := generatorreceiver.RenderTraces(topoPath, options, logger)
+		if err != nil {
+			return err
+		}
+		bytes, err = marshalTraces(td, format)
+		if err != nil {
+			return err
+		}
+	} else {
+		md, err := generatorreceiver.RenderMetrics(topoPath, options, logger)
+		if err != nil {
+			return err
+		}
+		bytes, err = marshalMetrics(md, format)
+		if err != nil {
+			return err
+		}
+	}
+	return os.WriteFile(out, bytes, 0644)
+}
+
+func marshalTraces(td ptrace.Traces, format string) ([]byte, error) {
+	if format == jsonFormat {
+		return (&ptrace.JSONMarshaler{}).MarshalTraces(td)
+	}
+	return (&ptrace.ProtoMarshaler{}).MarshalTraces(td)
+}
+
+func marshalMetrics(md pmetric.Metrics, format string) ([]byte, error) {
+	if format == jsonFormat {
+		return (&pmetric.JSONMarshaler{}).MarshalMetrics(md)
+	}
+	return (&pmetric.ProtoMarshaler{}).MarshalMetrics(md)
+}
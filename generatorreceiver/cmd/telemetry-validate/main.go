@@ -0,0 +1,53 @@
+// Command telemetry-validate checks a topology file and reports every problem found in it, with
+// the line it is on:
+//
+//	telemetry-validate examples/hipster_shop.yaml
+//
+// It exits with status 1 if the topology has errors, or with -strict, warnings.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/lightstep/telemetry-generator/generatorreceiver"
+)
+
+func main() {
+	asJSON := flag.Bool("json", false, "print the diagnostics as JSON")
+	strict := flag.Bool("strict", false, "fail on warnings too")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: telemetry-validate [-json] [-strict] <topology file, - for stdin>\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	topoPath := flag.Arg(0)
+	diagnostics, err := generatorreceiver.ValidateTopology(topoPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "telemetry-validate: %v\n", err)
+		os.Exit(1)
+	}
+
+	if *asJSON {
+		if diagnostics == nil {
+			diagnostics = []generatorreceiver.Diagnostic{}
+		}
+		out, _ := json.MarshalIndent(diagnostics, "", "  ")
+		fmt.Println(string(out))
+	} else {
+		for _, d := range diagnostics {
+			fmt.Printf("%s: %s\n", topoPath, d)
+		}
+	}
+
+	if generatorreceiver.HasErrors(diagnostics) || (*strict && len(diagnostics) > 0) {
+		os.Exit(1)
+	}
+}
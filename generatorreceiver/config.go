@@ -1,6 +1,9 @@
 package generatorreceiver
 
 import (
+	"fmt"
+	"time"
+
 	"go.opentelemetry.io/collector/config/confighttp"
 )
 
@@ -10,6 +13,134 @@ type Config struct {
 	Path string `mapstructure:"path"`
 	// Inline string containing the topo file
 	InlineFile string `mapstructure:"inline"`
+	// ReloadInterval is how often the topo file at Path is checked for changes, 0 disables reloading.
+	ReloadInterval time.Duration `mapstructure:"reload_interval"`
+	// Seed seeds all random generation, so the same topology and seed generate the same telemetry.
+	// 0 picks a different seed on every run.
+	Seed int64 `mapstructure:"seed"`
+	// Backfill generates the telemetry of a past time range instead of live telemetry.
+	Backfill BackfillConfig `mapstructure:"backfill"`
+	// Clock shifts or accelerates the time that live telemetry is generated on.
+	Clock ClockConfig `mapstructure:"clock"`
+	// Batch groups the generated spans and metrics into larger payloads, consumed by a pool of workers.
+	Batch BatchConfig `mapstructure:"batch"`
 	// ApiIngress holds config settings for HTTP server listening for requests.
 	ApiIngress confighttp.HTTPServerSettings `mapstructure:"api"`
 }
+
+// BackfillConfig is the time range to backfill: from Start to End, or the Duration before End.
+type BackfillConfig struct {
+	// Start and End are RFC 3339 timestamps, End defaults to now.
+	Start string `mapstructure:"start"`
+	End   string `mapstructure:"end"`
+	// Duration is used instead of Start, e.g. 168h backfills the week before End.
+	Duration time.Duration `mapstructure:"duration"`
+}
+
+// ClockConfig shifts the generated timestamps by Offset, and makes time pass Speed times as fast.
+type ClockConfig struct {
+	// Offset e.g. -24h generates yesterday's telemetry.
+	Offset time.Duration `mapstructure:"offset"`
+	// Speed e.g. 60 generates an hour of telemetry every minute, defaults to 1.
+	Speed float64 `mapstructure:"speed"`
+}
+
+// BatchConfig makes generators add their spans and metrics to a batch instead of consuming them
+// right away. A batch is queued once it holds Size spans or data points, or after Timeout, and
+// Workers consume the queued batches. Generators wait while the queue is full.
+type BatchConfig struct {
+	// Size is the number of spans or data points per batch, 0 disables batching.
+	Size int `mapstructure:"size"`
+	// Timeout is the longest a batch waits to fill up, defaults to DefaultBatchTimeout.
+	Timeout time.Duration `mapstructure:"timeout"`
+	// Workers is the number of batches consumed at once, defaults to DefaultBatchWorkers.
+	Workers int `mapstructure:"workers"`
+	// QueueSize is the number of batches waiting for a worker, defaults to twice the workers.
+	QueueSize int `mapstructure:"queue_size"`
+}
+
+func (cfg *Config) Validate() error {
+	if cfg.Clock.Speed < 0 {
+		return fmt.Errorf("invalid clock: speed cannot be negative")
+	}
+	if cfg.Batch.Size < 0 || cfg.Batch.Timeout < 0 || cfg.Batch.Workers < 0 || cfg.Batch.QueueSize < 0 {
+		return fmt.Errorf("invalid batch: size, timeout, workers and queue_size cannot be negative")
+	}
+	if !cfg.Backfill.enabled() {
+		return nil
+	}
+	if cfg.Clock.enabled() {
+		return fmt.Errorf("clock cannot be set when backfilling")
+	}
+	_, _, err := cfg.Backfill.timeRange(time.Now())
+	if err != nil {
+		return fmt.Errorf("invalid backfill: %w", err)
+	}
+	return nil
+}
+
+func (c ClockConfig) enabled() bool {
+	return c.Offset != 0 || c.GetSpeed() != 1
+}
+
+func (c ClockConfig) GetSpeed() float64 {
+	if c.Speed == 0 {
+		return 1
+	}
+	return c.Speed
+}
+
+func (b BatchConfig) enabled() bool {
+	return b.Size > 0
+}
+
+func (b BatchConfig) GetTimeout() time.Duration {
+	if b.Timeout == 0 {
+		return DefaultBatchTimeout
+	}
+	return b.Timeout
+}
+
+func (b BatchConfig) GetWorkers() int {
+	if b.Workers == 0 {
+		return DefaultBatchWorkers
+	}
+	return b.Workers
+}
+
+func (b BatchConfig) GetQueueSize() int {
+	if b.QueueSize == 0 {
+		return 2 * b.GetWorkers()
+	}
+	return b.QueueSize
+}
+
+func (b BackfillConfig) enabled() bool {
+	return b.Start != "" || b.Duration != 0
+}
+
+// timeRange returns the start and end of the backfill, with End defaulting to now.
+func (b BackfillConfig) timeRange(now time.Time) (start time.Time, end time.Time, err error) {
+	end = now
+	if b.End != "" {
+		end, err = time.Parse(time.RFC3339, b.End)
+		if err != nil {
+			return start, end, err
+		}
+	}
+	switch {
+	case b.Start != "" && b.Duration != 0:
+		return start, end, fmt.Errorf("only one of start and duration can be set")
+	case b.Start != "":
+		start, err = time.Parse(time.RFC3339, b.Start)
+		if err != nil {
+			return start, end, err
+		}
+	default:
+		start = end.Add(-b.Duration)
+	}
+	if !start.Before(end) {
+		return start, end, fmt.Errorf("start must be before end")
+	}
+	return start, end, nil
+}
@@ -11,6 +11,8 @@ import (
 const (
 	typeStr         = "generator"
 	DefaultTopoFile = "topo.json"
+	// DefaultReloadInterval is how often the topo file is checked for changes.
+	DefaultReloadInterval = 10 * time.Second
 	// The stability level of the exporter.
 	stability = component.StabilityLevelStable
 )
@@ -21,12 +23,14 @@ func NewFactory() receiver.Factory {
 		typeStr,
 		createDefaultConfig,
 		receiver.WithTraces(createTracesReceiver, stability),
-		receiver.WithMetrics(createMetricsReceiver, stability))
+		receiver.WithMetrics(createMetricsReceiver, stability),
+		receiver.WithLogs(createLogsReceiver, stability))
 }
 
 func createDefaultConfig() component.Config {
 	return &Config{
-		Path: DefaultTopoFile,
+		Path:           DefaultTopoFile,
+		ReloadInterval: DefaultReloadInterval,
 	}
 }
 
@@ -36,7 +40,7 @@ func createMetricsReceiver(
 	cfg component.Config,
 	consumer consumer.Metrics) (receiver.Metrics, error) {
 	rcfg := cfg.(*Config)
-	return newMetricReceiver(rcfg, consumer, params.Logger, time.Now().Unix())
+	return newMetricReceiver(rcfg, consumer, params, randomSeed(rcfg))
 }
 
 func createTracesReceiver(
@@ -45,5 +49,22 @@ func createTracesReceiver(
 	cfg component.Config,
 	consumer consumer.Traces) (receiver.Traces, error) {
 	rcfg := cfg.(*Config)
-	return newTraceReceiver(rcfg, consumer, params.Logger, time.Now().Unix())
+	return newTraceReceiver(rcfg, consumer, params, randomSeed(rcfg))
+}
+
+func createLogsReceiver(
+	ctx context.Context,
+	params receiver.CreateSettings,
+	cfg component.Config,
+	consumer consumer.Logs) (receiver.Logs, error) {
+	rcfg := cfg.(*Config)
+	return newLogReceiver(rcfg, consumer, params, randomSeed(rcfg))
+}
+
+// randomSeed returns the configured seed, or one based on the current time if there is none.
+func randomSeed(cfg *Config) int64 {
+	if cfg.Seed != 0 {
+		return cfg.Seed
+	}
+	return time.Now().Unix()
 }
@@ -3,14 +3,19 @@ package generatorreceiver
 import (
 	"context"
 	"fmt"
+	"hash/fnv"
 	"math/rand"
+	"sync"
 	"time"
 
 	"go.opentelemetry.io/collector/component"
 	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/ptrace"
 	"go.opentelemetry.io/collector/receiver"
 	"go.uber.org/zap"
 
+	"github.com/lightstep/telemetry-generator/generatorreceiver/internal/clock"
 	"github.com/lightstep/telemetry-generator/generatorreceiver/internal/cron"
 	"github.com/lightstep/telemetry-generator/generatorreceiver/internal/flags"
 	"github.com/lightstep/telemetry-generator/generatorreceiver/internal/generator"
@@ -21,45 +26,127 @@ type generatorReceiver struct {
 	logger         *zap.Logger
 	traceConsumer  consumer.Traces
 	metricConsumer consumer.Metrics
+	logConsumer    consumer.Logs
 	topoPath       string
 	topoInline     string
+	reloadInterval time.Duration
 	randomSeed     int64
-	tickers        []*time.Ticker
+	backfill       BackfillConfig
+	clockCfg       ClockConfig
+	batchCfg       BatchConfig
 	server         *httpServer
+
+	// The receiver is shared by the traces, metrics and logs pipelines, so Start and Shutdown
+	// are called once per pipeline; only the first call of each does anything.
+	mu      sync.Mutex
+	started bool
+	done    chan struct{}
+
+	// state of the currently loaded topology, see applyTopo.
+	topoFile            *topology.File
+	topoBytes           []byte
+	serviceFingerprints map[string]string
+	traceGenerators     map[string]*runningGenerator
+	metricGenerators    map[string][]*runningGenerator
+	// set while backfilling, see runBackfill
+	backfillClock *clock.Simulated
+	backfillEnd   time.Time
+	// restores the wall clock on shutdown, see ClockConfig
+	restoreClock func()
+	// set when batching, see BatchConfig
+	batcher *batcher
+	// counts of what was generated, see registerTelemetry
+	telemetry receiverTelemetry
 }
 
-func (g generatorReceiver) loadTopoFile(path string) (topoFile *topology.File, err error) {
+// loadTopoFile reads the topology from the inline config when set, otherwise from the topology path.
+func (g *generatorReceiver) loadTopoFile() ([]byte, error) {
+	if g.topoInline != "" {
+		g.logger.Info("reading topo from inline config")
+		return []byte(g.topoInline), nil
+	}
 	g.logger.Info("reading topo from file path", zap.String("path", g.topoPath))
-	topoFile, err = parseTopoFile(path)
-	if err != nil {
-		return nil, err
+	return readTopoFile(g.topoPath)
+}
+
+func (g *generatorReceiver) Start(ctx context.Context, host component.Host) error {
+	g.mu.Lock()
+	if g.started {
+		g.mu.Unlock()
+		return nil
+	}
+	g.started = true
+	g.done = make(chan struct{})
+	g.mu.Unlock()
+
+	if err := g.registerTelemetry(); err != nil {
+		g.mu.Lock()
+		g.started = false
+		g.mu.Unlock()
+		return fmt.Errorf("could not register telemetry: %w", err)
 	}
-	flags.Manager.LoadFlags(topoFile.Flags, g.logger)
 
-	err = topoFile.Topology.Load()
+	topoBytes, err := g.loadTopoFile()
 	if err != nil {
-		return nil, err
+		g.mu.Lock()
+		g.started = false
+		g.mu.Unlock()
+		_ = g.unregisterTelemetry()
+		return fmt.Errorf("could not load topo file: %w", err)
 	}
 
-	return topoFile, nil
-}
+	restoreClock := func() {}
+	if g.clockCfg.enabled() {
+		restoreClock = clock.Set(clock.NewScaled(time.Now().Add(g.clockCfg.Offset), g.clockCfg.GetSpeed()))
+	} else if g.backfill.enabled() {
+		// the topology is loaded on the simulated clock as well, e.g. so that pods start at the
+		// beginning of the backfill.
+		start, end, err := g.backfill.timeRange(time.Now())
+		g.mu.Lock()
+		if err != nil {
+			g.started = false
+			g.mu.Unlock()
+			_ = g.unregisterTelemetry()
+			return fmt.Errorf("invalid backfill: %w", err)
+		}
+		g.backfillClock, g.backfillEnd = clock.NewSimulated(start), end
+		g.mu.Unlock()
+		restoreClock = clock.Set(g.backfillClock)
+	}
 
-func (g generatorReceiver) Start(ctx context.Context, host component.Host) error {
-	topoFile, err := g.loadTopoFile(g.topoPath)
-	if err != nil {
-		return fmt.Errorf("could not load topo file: %w", err)
+	if g.batchCfg.enabled() {
+		g.mu.Lock()
+		g.batcher = newBatcher(g.batchCfg, g.logger, &g.telemetry, g.traceConsumer, g.metricConsumer)
+		g.batcher.start()
+		g.mu.Unlock()
 	}
 
-	err = validateConfiguration(*topoFile)
+	err = g.applyTopo(topoBytes)
 	if err != nil {
-		return fmt.Errorf("could not validate topo file: %w", err)
+		restoreClock()
+		g.mu.Lock()
+		g.started = false
+		g.backfillClock = nil
+		b := g.batcher
+		g.batcher = nil
+		g.mu.Unlock()
+		_ = g.unregisterTelemetry()
+		if b != nil {
+			b.shutdown()
+		}
+		return err
 	}
 
 	g.logger.Info("starting flag manager", zap.Int("flag_count", flags.Manager.FlagCount()))
-	cron.Start()
-
-	// rand is used to generate seeds the underlying *rand.Rand
-	generatorRand := rand.New(rand.NewSource(g.randomSeed))
+	switch {
+	case g.backfill.enabled():
+		go g.runBackfill(g.done, restoreClock)
+	case g.clockCfg.enabled():
+		g.restoreClock = restoreClock
+		cron.StartPolling(time.Second)
+	default:
+		cron.Start()
+	}
 
 	if g.server != nil {
 		err := g.server.Start(ctx, host)
@@ -68,174 +155,367 @@ func (g generatorReceiver) Start(ctx context.Context, host component.Host) error
 		}
 	}
 
-	for _, s := range topoFile.Topology.Services {
+	if g.topoInline == "" && g.topoPath != stdinTopoPath && g.reloadInterval > 0 && !g.backfill.enabled() {
+		go g.watchTopoFile(g.done, topoBytes)
+	}
+
+	return nil
+}
+
+// applyTopo parses and validates topoBytes, then swaps it in for the running topology. Only the
+// generators whose part of the topology changed are restarted; flags keep their state.
+func (g *generatorReceiver) applyTopo(topoBytes []byte) error {
+	topoFile, err := parseTopo(topoBytes)
+	if err != nil {
+		return fmt.Errorf("could not load topo file: %w", err)
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if !g.started {
+		return fmt.Errorf("receiver is not running")
+	}
+	if g.backfillClock != nil && g.topoFile != nil {
+		return fmt.Errorf("the topology cannot be reloaded while backfilling")
+	}
+
+	err = flags.Manager.ReloadFlags(topoFile.Flags, g.logger, func(fm *flags.FlagManager) error {
+		err := topoFile.Topology.Load()
+		if err != nil {
+			return fmt.Errorf("could not load topo file: %w", err)
+		}
+		err = validateConfiguration(*topoFile, fm)
+		if err != nil {
+			return fmt.Errorf("could not validate topo file: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	fingerprints := serviceFingerprints(topoFile)
+	for name, s := range topoFile.Topology.Services {
+		if g.serviceUnchanged(name, fingerprints) {
+			// unchanged services keep their pods and the state of their generators.
+			topoFile.Topology.Services[name] = g.topoFile.Topology.Services[name]
+			continue
+		}
 		for i := range s.ResourceAttributeSets {
 			k := s.ResourceAttributeSets[i].Kubernetes
 			if k == nil {
 				continue
 			}
 			k.Cfg = topoFile.Config
-			k.CreatePods(s.ServiceName, generatorRand)
+			k.CreatePods(s.ServiceName, g.newRand(fmt.Sprintf("pods/%s/%d", name, i)))
 		}
 	}
 
 	if g.metricConsumer != nil {
-		for _, s := range topoFile.Topology.Services {
+		g.restartMetricGenerators(topoFile, fingerprints)
+	}
+	if g.traceConsumer != nil || g.logConsumer != nil {
+		g.restartTraceGenerators(topoFile, fingerprints)
+	}
+
+	g.topoFile = topoFile
+	g.topoBytes = topoBytes
+	g.serviceFingerprints = fingerprints
+	return nil
+}
 
-			// Service defined metrics
-			for _, m := range s.Metrics {
-				metricTicker := g.startMetricGenerator(ctx, s.ServiceName, m, generatorRand)
-				g.tickers = append(g.tickers, metricTicker)
+func (g *generatorReceiver) restartMetricGenerators(topoFile *topology.File, fingerprints map[string]string) {
+	running := g.metricGenerators
+	g.metricGenerators = make(map[string][]*runningGenerator)
+
+	for name, s := range topoFile.Topology.Services {
+		if generators, ok := running[name]; ok && g.serviceUnchanged(name, fingerprints) {
+			g.metricGenerators[name] = generators
+			delete(running, name)
+			continue
+		}
+
+		// Service defined metrics
+		for _, m := range s.Metrics {
+			g.metricGenerators[name] = append(g.metricGenerators[name], g.startMetricGenerator(s, m, len(g.metricGenerators[name])))
+		}
+
+		// Service kubernetes auto-generated metrics
+		for i := range s.ResourceAttributeSets {
+			resource := &s.ResourceAttributeSets[i]
+			// For each resource generate k8s metrics if enabled
+			if resource.Kubernetes == nil {
+				continue
 			}
+			k8sMetrics := resource.Kubernetes.GenerateMetrics()
+			for i := range k8sMetrics {
+				// keep the same flags as the resources.
+				k8sMetrics[i].EmbeddedFlags = resource.EmbeddedFlags
 
-			// Service kubernetes auto-generated metrics
-			for i := range s.ResourceAttributeSets {
-				resource := &s.ResourceAttributeSets[i]
-				// For each resource generate k8s metrics if enabled
-				if resource.Kubernetes == nil {
-					continue
-				}
-				k8sMetrics := resource.Kubernetes.GenerateMetrics()
-				for i := range k8sMetrics {
-					// keep the same flags as the resources.
-					k8sMetrics[i].EmbeddedFlags = resource.EmbeddedFlags
-
-					metricTicker := g.startMetricGenerator(ctx, s.ServiceName, k8sMetrics[i], generatorRand)
-					g.tickers = append(g.tickers, metricTicker)
-				}
+				g.metricGenerators[name] = append(g.metricGenerators[name], g.startMetricGenerator(s, k8sMetrics[i], len(g.metricGenerators[name])))
 			}
 		}
+	}
 
+	// whatever is left belongs to services that changed or were removed.
+	for _, generators := range running {
+		for _, r := range generators {
+			r.stop()
+		}
 	}
-	if g.traceConsumer != nil {
-		for _, rootRoute := range topoFile.RootRoutes {
-			traceTicker := time.NewTicker(time.Duration(360000/rootRoute.TracesPerHour) * time.Millisecond)
-			g.tickers = append(g.tickers, traceTicker)
-			done := make(chan bool)
-			svc := rootRoute.Service
-			route := rootRoute.Route
-			rootRoute := rootRoute
+}
 
-			// rand.Rand is not safe to use in different go routines,
-			// create one for each go routine, but use the generatorRand to
-			// generate the seed.
-			routeRand := rand.New(rand.NewSource(generatorRand.Int63()))
+func (g *generatorReceiver) restartTraceGenerators(topoFile *topology.File, fingerprints map[string]string) {
+	running := g.traceGenerators
+	g.traceGenerators = make(map[string]*runningGenerator)
 
-			go func() {
-				g.logger.Info("generating traces", zap.String("service", svc), zap.String("route", route))
-				traceGen := generator.NewTraceGenerator(topoFile.Topology, routeRand, svc, route)
-				for {
-					select {
-					case <-done:
-						return
-					case <-traceTicker.C:
-						if rootRoute.ShouldGenerate() {
-							traces := traceGen.Generate(time.Now().UnixNano())
-							err := g.traceConsumer.ConsumeTraces(context.Background(), *traces)
-							if err != nil {
-								g.logger.Error("consume error", zap.Error(err))
-							}
+	for _, key := range rootRouteFingerprints(topoFile, fingerprints) {
+		if r, ok := running[key.fingerprint]; ok {
+			g.traceGenerators[key.fingerprint] = r
+			delete(running, key.fingerprint)
+			continue
+		}
+		g.traceGenerators[key.fingerprint] = g.startTraceGenerator(topoFile.Topology, topoFile.RootRoutes[key.index], key.index)
+	}
 
-						}
-					}
-				}
-			}()
+	// whatever is left belongs to root routes that changed or were removed.
+	for _, r := range running {
+		r.stop()
+	}
+}
+
+// newRand returns the random source of the generator identified by key. Each source is seeded from
+// the receiver's seed and its key instead of being drawn from a shared source, so a generator gets
+// the same random stream on every run and reload, whatever order the services are started in.
+func (g *generatorReceiver) newRand(key string) *rand.Rand {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	return rand.New(rand.NewSource(g.randomSeed ^ int64(h.Sum64())))
+}
+
+func (g *generatorReceiver) startTraceGenerator(topo *topology.Topology, rootRoute topology.RootRoute, index int) *runningGenerator {
+	svc := rootRoute.Service
+	route := rootRoute.Route
+
+	// rand.Rand is not safe to use in different go routines,
+	// create one for each go routine.
+	routeRand := g.newRand(fmt.Sprintf("traces/%d", index))
+	traceGen := generator.NewTraceGenerator(topo, routeRand, svc, route)
+
+	g.logger.Info("generating traces", zap.String("service", svc), zap.String("route", route))
+	scheduler := newRateScheduler(rootRoute, g.newRand(fmt.Sprintf("arrivals/%d", index)))
+	// Shutdown drops the batcher while a tick may still be running, so each generator keeps a
+	// reference to the batcher it was started with instead of reading g.batcher on every tick.
+	batcher := g.batcher
+	r := newRunningGenerator(scheduler.period(), func() {
+		arrivals := scheduler.arrivals(clock.Now())
+		if len(arrivals) > 0 && rootRoute.ShouldGenerate() {
+			g.generateTraces(traceGen, batcher, arrivals)
 		}
+	})
+	r.interval = scheduler.next
+	if g.backfillClock == nil {
+		r.start(g.clockCfg.GetSpeed())
 	}
+	return r
+}
 
-	return nil
+// generateTraces generates a trace starting at each of arrivals, and consumes them all at once,
+// or adds them to batcher unless it is nil.
+func (g *generatorReceiver) generateTraces(traceGen *generator.TraceGenerator, batcher *batcher, arrivals []time.Time) {
+	traces, logs := ptrace.NewTraces(), plog.NewLogs()
+	for _, arrival := range arrivals {
+		if g.logConsumer == nil {
+			traceGen.Generate(arrival.UnixNano()).ResourceSpans().MoveAndAppendTo(traces.ResourceSpans())
+			continue
+		}
+		t, l := traceGen.GenerateWithLogs(arrival.UnixNano())
+		t.ResourceSpans().MoveAndAppendTo(traces.ResourceSpans())
+		l.ResourceLogs().MoveAndAppendTo(logs.ResourceLogs())
+	}
+
+	if g.traceConsumer != nil && traces.ResourceSpans().Len() > 0 {
+		g.telemetry.addTraces(traces)
+		if batcher != nil {
+			batcher.addTraces(traces)
+		} else if err := g.traceConsumer.ConsumeTraces(context.Background(), traces); err != nil {
+			g.consumeError(err)
+		}
+	}
+	if logs.LogRecordCount() > 0 {
+		g.telemetry.addLogs(logs)
+		err := g.logConsumer.ConsumeLogs(context.Background(), logs)
+		if err != nil {
+			g.consumeError(err)
+		}
+	}
 }
 
-func (g *generatorReceiver) startMetricGenerator(
-	ctx context.Context,
-	serviceName string,
-	m topology.Metric,
-	random *rand.Rand,
-) *time.Ticker {
-	// TODO: do we actually need to generate every second?
-	metricTicker := time.NewTicker(topology.DefaultMetricTickerPeriod)
-	go func() {
-		g.logger.Info("generating metrics", zap.String("service", serviceName), zap.String("name", m.Name), zap.String("flag_set", m.EmbeddedFlags.FlagSet), zap.String("flag_unset", m.EmbeddedFlags.FlagUnset))
-		metricGen := generator.NewMetricGenerator(g.randomSeed)
-		for range metricTicker.C {
-			m.Pod.RestartIfNeeded(m.EmbeddedFlags, g.logger, random)
-
-			if metrics, report := metricGen.Generate(&m, serviceName); report {
-				err := g.metricConsumer.ConsumeMetrics(ctx, metrics)
-				if err != nil {
-					g.logger.Error("consume error", zap.Error(err))
-				}
-			}
+func (g *generatorReceiver) consumeError(err error) {
+	g.telemetry.addConsumeError()
+	g.logger.Error("consume error", zap.Error(err))
+}
+
+func (g *generatorReceiver) startMetricGenerator(s *topology.ServiceTier, m topology.Metric, index int) *runningGenerator {
+	serviceName := s.ServiceName
+	// see startTraceGenerator
+	random := g.newRand(fmt.Sprintf("metrics/%s/%d", serviceName, index))
+	metricGen := generator.NewMetricGenerator(random.Int63())
+
+	interval := s.GetMetricInterval(&m)
+	batcher := g.batcher
+	g.logger.Info("generating metrics", zap.String("service", serviceName), zap.String("name", m.Name), zap.Duration("interval", interval), zap.String("flag_set", m.EmbeddedFlags.FlagSet), zap.String("flag_unset", m.EmbeddedFlags.FlagUnset))
+	r := newRunningGenerator(interval, func() {
+		if m.Pod.RestartIfNeeded(m.EmbeddedFlags, g.logger, random) {
+			g.telemetry.addPodRestart()
 		}
-	}()
 
-	return metricTicker
+		if metrics, report := metricGen.Generate(&m, serviceName); report {
+			g.telemetry.addMetrics(metrics)
+			if batcher != nil {
+				batcher.addMetrics(metrics)
+			} else if err := g.metricConsumer.ConsumeMetrics(context.Background(), metrics); err != nil {
+				g.consumeError(err)
+			}
+		}
+	})
+	// metrics tick on the boundaries of their interval, so the data points of all metrics with
+	// the same interval line up
+	r.aligned = true
+	if g.backfillClock == nil {
+		r.start(g.clockCfg.GetSpeed())
+	}
+	return r
 }
 
 var genReceiver = generatorReceiver{}
 
-func (g generatorReceiver) Shutdown(_ context.Context) error {
-	for _, t := range g.tickers {
-		t.Stop()
+func (g *generatorReceiver) Shutdown(ctx context.Context) error {
+	g.mu.Lock()
+	if !g.started {
+		g.mu.Unlock()
+		return nil
 	}
+	g.started = false
+
+	close(g.done)
+	for _, r := range g.traceGenerators {
+		r.stop()
+	}
+	for _, generators := range g.metricGenerators {
+		for _, r := range generators {
+			r.stop()
+		}
+	}
+	g.traceGenerators = nil
+	g.metricGenerators = nil
+	g.topoFile = nil
+	g.serviceFingerprints = nil
+	g.backfillClock = nil
+	if g.restoreClock != nil {
+		g.restoreClock()
+		g.restoreClock = nil
+	}
+	// the receiver is shared, so the next Start must not find the batcher that is shut down here
+	b := g.batcher
+	g.batcher = nil
+	g.mu.Unlock()
+
+	// generators are stopped, so what they generated can be consumed
+	if b != nil {
+		b.shutdown()
+	}
+
 	cron.Stop()
-	return nil
+	err := g.unregisterTelemetry()
+	if g.server != nil && g.server.server != nil {
+		if serverErr := g.server.Shutdown(ctx); serverErr != nil {
+			return serverErr
+		}
+	}
+	return err
+}
+
+// setup applies the settings shared by the traces, metrics and logs pipelines.
+func (g *generatorReceiver) setup(config *Config, logger *zap.Logger, randomSeed int64) {
+	g.logger = logger
+	g.topoPath = config.Path
+	g.topoInline = config.InlineFile
+	g.reloadInterval = config.ReloadInterval
+	g.randomSeed = randomSeed
+	g.backfill = config.Backfill
+	g.clockCfg = config.Clock
+	g.batchCfg = config.Batch
+
+	if config.ApiIngress.Endpoint != "" && g.server == nil {
+		server, err := newHTTPServer(config, logger, g)
+		if err != nil {
+			logger.Fatal("could not start http server")
+		}
+		g.server = server
+	}
 }
 
 func newMetricReceiver(config *Config,
 	consumer consumer.Metrics,
-	logger *zap.Logger, randomSeed int64) (receiver.Metrics, error) {
+	params receiver.CreateSettings, randomSeed int64) (receiver.Metrics, error) {
 
 	if consumer == nil {
 		return nil, component.ErrNilNextConsumer
 	}
 
-	genReceiver.logger = logger
-	genReceiver.topoPath = config.Path
-	genReceiver.topoInline = config.InlineFile
-	genReceiver.randomSeed = randomSeed
+	genReceiver.setup(config, params.Logger, randomSeed)
+	genReceiver.setTelemetry(params.TelemetrySettings, params.ID)
 	genReceiver.metricConsumer = consumer
-
-	// TODO: share server between trace and metric pipelines
-	if config.ApiIngress.Endpoint != "" {
-		server, err := newHTTPServer(config, logger)
-		if err != nil {
-			logger.Fatal("could not start http server")
-		}
-		genReceiver.server = server
-	}
-
 	return &genReceiver, nil
 }
 
 func newTraceReceiver(config *Config,
 	consumer consumer.Traces,
-	logger *zap.Logger, randomSeed int64) (receiver.Traces, error) {
+	params receiver.CreateSettings, randomSeed int64) (receiver.Traces, error) {
 
 	if consumer == nil {
 		return nil, component.ErrNilNextConsumer
 	}
 
-	genReceiver.logger = logger
-	genReceiver.topoPath = config.Path
-	genReceiver.randomSeed = randomSeed
+	genReceiver.setup(config, params.Logger, randomSeed)
+	genReceiver.setTelemetry(params.TelemetrySettings, params.ID)
 	genReceiver.traceConsumer = consumer
 	return &genReceiver, nil
 }
 
-func validateConfiguration(topoFile topology.File) error {
-	err := flags.Manager.ValidateFlags()
+func newLogReceiver(config *Config,
+	consumer consumer.Logs,
+	params receiver.CreateSettings, randomSeed int64) (receiver.Logs, error) {
+
+	if consumer == nil {
+		return nil, component.ErrNilNextConsumer
+	}
+
+	genReceiver.setup(config, params.Logger, randomSeed)
+	genReceiver.setTelemetry(params.TelemetrySettings, params.ID)
+	genReceiver.logConsumer = consumer
+	return &genReceiver, nil
+}
+
+func validateConfiguration(topoFile topology.File, fm *flags.FlagManager) error {
+	err := fm.ValidateFlags()
 	if err != nil {
 		return fmt.Errorf("validation of flag configuration failed: %v", err)
 	}
 
+	err = topoFile.Topology.ValidateDependencies(fm)
+	if err != nil {
+		return fmt.Errorf("validation of dependency configuration failed: %v", err)
+	}
+
 	for _, service := range topoFile.Topology.Services {
-		err = service.Validate(*topoFile.Topology)
+		err = service.Validate(*topoFile.Topology, fm)
 		if err != nil {
 			return fmt.Errorf("validation of service configuration failed: %v", err)
 		}
 	}
-	err = topoFile.ValidateRootRoutes()
+	err = topoFile.ValidateRootRoutes(fm)
 	if err != nil {
 		return fmt.Errorf("validation of rootRoute configuration failed: %v", err)
 	}
@@ -0,0 +1,357 @@
+package generatorreceiver
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/consumer/consumertest"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.uber.org/zap"
+
+	"github.com/lightstep/telemetry-generator/generatorreceiver/internal/clock"
+	"github.com/lightstep/telemetry-generator/generatorreceiver/internal/flags"
+)
+
+const reloadTestTopo = `
+topology:
+  services:
+    frontend:
+      routes:
+        /product:
+          downstreamCalls:
+            - service: cartservice
+              route: /GetCart
+          maxLatencyMillis: 100
+    cartservice:
+      routes:
+        /GetCart:
+          maxLatencyMillis: 50
+    checkoutservice:
+      metrics:
+        - name: orders
+          type: Sum
+          min: 1
+          max: 10
+      routes:
+        /PlaceOrder:
+          maxLatencyMillis: CHECKOUT_LATENCY
+flags:
+  - name: checkout_errors
+rootRoutes:
+  - service: frontend
+    route: /product
+    tracesPerHour: 3600
+  - service: checkoutservice
+    route: /PlaceOrder
+    tracesPerHour: 3600
+`
+
+func reloadTestTopoWithLatency(latency string) []byte {
+	return []byte(strings.Replace(reloadTestTopo, "CHECKOUT_LATENCY", latency, 1))
+}
+
+func newTestReceiver(t *testing.T, config *Config) *generatorReceiver {
+	flags.Manager.Clear()
+	g := &generatorReceiver{}
+	g.setup(config, zap.NewNop(), 123)
+	g.traceConsumer = new(consumertest.TracesSink)
+	g.metricConsumer = new(consumertest.MetricsSink)
+	require.NoError(t, g.Start(context.Background(), componenttest.NewNopHost()))
+	t.Cleanup(func() { require.NoError(t, g.Shutdown(context.Background())) })
+	return g
+}
+
+func TestGeneratorReceiver_ApplyTopo(t *testing.T) {
+	topoPath := filepath.Join(t.TempDir(), "topo.yaml")
+	require.NoError(t, os.WriteFile(topoPath, reloadTestTopoWithLatency("200"), 0600))
+	g := newTestReceiver(t, &Config{Path: topoPath})
+
+	require.Len(t, g.traceGenerators, 2)
+	require.Len(t, g.metricGenerators["checkoutservice"], 1)
+	flags.Manager.GetFlag("checkout_errors").Enable()
+
+	traceGenerators := make(map[string]*runningGenerator)
+	for k, v := range g.traceGenerators {
+		traceGenerators[k] = v
+	}
+	frontend := g.topoFile.Topology.GetServiceTier("frontend")
+	checkoutMetrics := g.metricGenerators["checkoutservice"][0]
+
+	// only the checkoutservice changes
+	require.NoError(t, g.reloadTopo(reloadTestTopoWithLatency("300")))
+
+	require.Len(t, g.traceGenerators, 2)
+	kept, restarted := 0, 0
+	for k, v := range g.traceGenerators {
+		if traceGenerators[k] == v {
+			kept++
+		} else {
+			restarted++
+		}
+	}
+	require.Equal(t, 1, kept)
+	require.Equal(t, 1, restarted)
+	require.Same(t, frontend, g.topoFile.Topology.GetServiceTier("frontend"))
+	require.NotSame(t, checkoutMetrics, g.metricGenerators["checkoutservice"][0])
+	require.Equal(t, int64(300), g.topoFile.Topology.GetServiceTier("checkoutservice").GetRoute("/PlaceOrder").MaxLatencyMillis)
+	require.True(t, flags.Manager.GetFlag("checkout_errors").Active())
+
+	// an invalid topology is rejected and the running one is kept
+	err := g.reloadTopo([]byte(strings.Replace(string(reloadTestTopoWithLatency("300")), "route: /GetCart", "route: /Missing", 1)))
+	require.Error(t, err)
+	require.Equal(t, int64(300), g.topoFile.Topology.GetServiceTier("checkoutservice").GetRoute("/PlaceOrder").MaxLatencyMillis)
+	require.NotNil(t, flags.Manager.GetFlag("checkout_errors"))
+	require.True(t, flags.Manager.GetFlag("checkout_errors").Active())
+}
+
+func TestGeneratorReceiver_WatchTopoFile(t *testing.T) {
+	topoPath := filepath.Join(t.TempDir(), "topo.yaml")
+	require.NoError(t, os.WriteFile(topoPath, reloadTestTopoWithLatency("200"), 0600))
+	g := newTestReceiver(t, &Config{Path: topoPath, ReloadInterval: 10 * time.Millisecond})
+
+	require.NoError(t, os.WriteFile(topoPath, reloadTestTopoWithLatency("400"), 0600))
+	require.Eventually(t, func() bool {
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		return g.topoFile.Topology.GetServiceTier("checkoutservice").GetRoute("/PlaceOrder").MaxLatencyMillis == 400
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestGeneratorReceiver_NewRand(t *testing.T) {
+	require.Equal(t, int64(7), randomSeed(&Config{Seed: 7}))
+	require.NotZero(t, randomSeed(&Config{}))
+
+	g := &generatorReceiver{randomSeed: 7}
+	require.Equal(t, g.newRand("traces/0").Int63(), g.newRand("traces/0").Int63())
+	require.NotEqual(t, g.newRand("traces/0").Int63(), g.newRand("traces/1").Int63())
+
+	other := &generatorReceiver{randomSeed: 8}
+	require.NotEqual(t, g.newRand("traces/0").Int63(), other.newRand("traces/0").Int63())
+}
+
+const backfillTestTopo = `
+topology:
+  services:
+    frontend:
+      metrics:
+        - name: batch_jobs
+          type: Gauge
+          min: 1
+          max: 10
+          flag_set: nightly_batch
+      routes:
+        /product:
+          maxLatencyMillis: 100
+flags:
+  - name: nightly_batch
+    cron:
+      start: "0 1 * * *"
+      end: "0 2 * * *"
+rootRoutes:
+  - service: frontend
+    route: /product
+    tracesPerHour: 3600
+`
+
+func TestGeneratorReceiver_Backfill(t *testing.T) {
+	topoPath := filepath.Join(t.TempDir(), "topo.yaml")
+	require.NoError(t, os.WriteFile(topoPath, []byte(backfillTestTopo), 0600))
+	backfill := BackfillConfig{Start: "2023-01-01T00:30:00Z", End: "2023-01-01T02:30:00Z"}
+	g := newTestReceiver(t, &Config{Path: topoPath, Backfill: backfill})
+	traces := g.traceConsumer.(*consumertest.TracesSink)
+	metrics := g.metricConsumer.(*consumertest.MetricsSink)
+
+	// a trace every second for 2 hours, and a metric every 15 seconds while the cron flag is on
+	require.Eventually(t, func() bool {
+		return traces.SpanCount() == 7200 && metrics.DataPointCount() == 240
+	}, 30*time.Second, 10*time.Millisecond)
+
+	start, end, err := backfill.timeRange(time.Now())
+	require.NoError(t, err)
+	for _, td := range traces.AllTraces() {
+		ts := td.ResourceSpans().At(0).ScopeSpans().At(0).Spans().At(0).StartTimestamp().AsTime()
+		require.False(t, ts.Before(start))
+		require.True(t, ts.Before(end))
+	}
+	for _, md := range metrics.AllMetrics() {
+		ts := md.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(0).Gauge().DataPoints().At(0).Timestamp().AsTime()
+		require.False(t, ts.Before(start.Add(30*time.Minute)))
+		require.True(t, ts.Before(start.Add(90*time.Minute)))
+	}
+
+	// the topology cannot change in the middle of a backfill
+	require.Error(t, g.reloadTopo([]byte(backfillTestTopo)))
+}
+
+const metricIntervalTestTopo = `
+topology:
+  services:
+    frontend:
+      interval: 30s
+      metrics:
+        - name: queue_length
+          type: Gauge
+          min: 1
+          max: 10
+        - name: disk_usage
+          type: Gauge
+          min: 1
+          max: 10
+          interval: 5m
+      routes:
+        /product:
+          maxLatencyMillis: 100
+`
+
+func TestGeneratorReceiver_MetricInterval(t *testing.T) {
+	topoPath := filepath.Join(t.TempDir(), "topo.yaml")
+	require.NoError(t, os.WriteFile(topoPath, []byte(metricIntervalTestTopo), 0600))
+	// the backfill starts in the middle of an interval
+	backfill := BackfillConfig{Start: "2023-01-01T00:00:10Z", End: "2023-01-01T01:00:10Z"}
+	g := newTestReceiver(t, &Config{Path: topoPath, Backfill: backfill})
+	metrics := g.metricConsumer.(*consumertest.MetricsSink)
+
+	// the service's metric every 30 seconds, and the other one every 5 minutes
+	require.Eventually(t, func() bool {
+		return metrics.DataPointCount() == 120+12
+	}, 30*time.Second, 10*time.Millisecond)
+
+	counts := make(map[string]int)
+	for _, md := range metrics.AllMetrics() {
+		m := md.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(0)
+		counts[m.Name()]++
+		interval := 30 * time.Second
+		if m.Name() == "disk_usage" {
+			interval = 5 * time.Minute
+		}
+		// ticks are aligned on the interval, whenever the generator started
+		ts := m.Gauge().DataPoints().At(0).Timestamp().AsTime()
+		require.Equal(t, ts, ts.Truncate(interval), m.Name())
+	}
+	require.Equal(t, map[string]int{"queue_length": 120, "disk_usage": 12}, counts)
+}
+
+func TestRunningGenerator_Aligned(t *testing.T) {
+	period := 20 * time.Millisecond
+	ticks := make(chan time.Time, 10)
+	r := newRunningGenerator(period, func() {
+		select {
+		case ticks <- clock.Now():
+		default:
+		}
+	})
+	r.aligned = true
+	r.start(1)
+	defer r.stop()
+
+	// every tick happens once its boundary has passed, so no two ticks fall into the same period
+	var last time.Time
+	for i := 0; i < 5; i++ {
+		boundary := (<-ticks).Truncate(period)
+		require.True(t, boundary.After(last))
+		last = boundary
+	}
+}
+
+const rateShapeTestTopo = `
+topology:
+  services:
+    frontend:
+      routes:
+        /product:
+          maxLatencyMillis: 100
+        /cart:
+          maxLatencyMillis: 100
+rootRoutes:
+  - service: frontend
+    route: /product
+    tracesPerHour: 3600
+    rateShape:
+      shape: square
+      period: 2h
+  - service: frontend
+    route: /cart
+    tracesPerHour: 3600
+    arrival: poisson
+`
+
+func TestGeneratorReceiver_RateShape(t *testing.T) {
+	topoPath := filepath.Join(t.TempDir(), "topo.yaml")
+	require.NoError(t, os.WriteFile(topoPath, []byte(rateShapeTestTopo), 0600))
+	backfill := BackfillConfig{Start: "2023-01-01T00:00:00Z", End: "2023-01-01T02:00:00Z"}
+	g := newTestReceiver(t, &Config{Path: topoPath, Backfill: backfill})
+	traces := g.traceConsumer.(*consumertest.TracesSink)
+
+	// the square shape is off for the first hour and on for the second
+	productSpans := func() (product []ptrace.Span, cart int) {
+		for _, td := range traces.AllTraces() {
+			span := td.ResourceSpans().At(0).ScopeSpans().At(0).Spans().At(0)
+			if span.Name() == "/product" {
+				product = append(product, span)
+			} else {
+				cart++
+			}
+		}
+		return product, cart
+	}
+	require.Eventually(t, func() bool {
+		product, _ := productSpans()
+		return len(product) == 3600
+	}, 30*time.Second, 10*time.Millisecond)
+
+	start, _, err := backfill.timeRange(time.Now())
+	require.NoError(t, err)
+	product, cart := productSpans()
+	for _, span := range product {
+		require.False(t, span.StartTimestamp().AsTime().Before(start.Add(time.Hour)))
+	}
+	// poisson arrivals average the same rate as uniform ones
+	require.InDelta(t, 7200, cart, 300)
+}
+
+func TestBackfillConfig_TimeRange(t *testing.T) {
+	now := time.Date(2023, 1, 8, 0, 0, 0, 0, time.UTC)
+	start, end, err := BackfillConfig{Duration: 7 * 24 * time.Hour}.timeRange(now)
+	require.NoError(t, err)
+	require.Equal(t, time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC), start)
+	require.Equal(t, now, end)
+
+	_, _, err = BackfillConfig{Start: "2023-01-01T00:00:00Z", Duration: time.Hour}.timeRange(now)
+	require.Error(t, err)
+	_, _, err = BackfillConfig{Start: "2023-01-09T00:00:00Z"}.timeRange(now)
+	require.Error(t, err)
+	_, _, err = BackfillConfig{Start: "yesterday"}.timeRange(now)
+	require.Error(t, err)
+}
+
+func TestGeneratorReceiver_Clock(t *testing.T) {
+	topoPath := filepath.Join(t.TempDir(), "topo.yaml")
+	require.NoError(t, os.WriteFile(topoPath, reloadTestTopoWithLatency("200"), 0600))
+	g := newTestReceiver(t, &Config{Path: topoPath, Clock: ClockConfig{Offset: -24 * time.Hour, Speed: 3600}})
+	traces := g.traceConsumer.(*consumertest.TracesSink)
+
+	// 10 traces a second on the clock are 36000 a second on the wall clock
+	require.Eventually(t, func() bool { return traces.SpanCount() >= 100 }, 5*time.Second, 10*time.Millisecond)
+	require.NoError(t, g.Shutdown(context.Background()))
+
+	td := traces.AllTraces()[0]
+	ts := td.ResourceSpans().At(0).ScopeSpans().At(0).Spans().At(0).StartTimestamp().AsTime()
+	require.True(t, ts.Before(time.Now().Add(-23*time.Hour)))
+	// the wall clock is back once the receiver shuts down
+	require.WithinDuration(t, time.Now(), clock.Now(), time.Second)
+}
+
+func TestConfig_Validate(t *testing.T) {
+	require.NoError(t, (&Config{}).Validate())
+	require.NoError(t, (&Config{Clock: ClockConfig{Offset: -time.Hour, Speed: 2}}).Validate())
+	require.Error(t, (&Config{Clock: ClockConfig{Speed: -1}}).Validate())
+	require.Error(t, (&Config{Clock: ClockConfig{Speed: 2}, Backfill: BackfillConfig{Duration: time.Hour}}).Validate())
+	require.Error(t, (&Config{Backfill: BackfillConfig{Start: "yesterday"}}).Validate())
+	require.Error(t, (&Config{Batch: BatchConfig{Size: 100, Workers: -1}}).Validate())
+}
@@ -10,6 +10,9 @@ import (
 	"gopkg.in/yaml.v3"
 )
 
+// stdinTopoPath is the topology path that reads the topology from stdin.
+const stdinTopoPath = "-"
+
 func hasAnySuffix(s string, suffixes []string) bool {
 	for _, suffix := range suffixes {
 		if strings.HasSuffix(s, suffix) {
@@ -21,23 +24,43 @@ func hasAnySuffix(s string, suffixes []string) bool {
 }
 
 func parseTopoFile(topoPath string) (*topology.File, error) {
-	var topo topology.File
-	topoFile, err := os.Open(topoPath)
+	byteValue, err := readTopoFile(topoPath)
 	if err != nil {
 		return nil, err
 	}
-	defer topoFile.Close()
+	return parseTopo(byteValue)
+}
+
+func readTopoFile(topoPath string) ([]byte, error) {
+	if topoPath == stdinTopoPath {
+		return io.ReadAll(os.Stdin)
+	}
 
-	byteValue, _ := io.ReadAll(topoFile)
 	lowerTopoPath := strings.ToLower(topoPath)
-	if hasAnySuffix(lowerTopoPath, []string{".yaml", ".yml"}) {
-		err = yaml.Unmarshal(byteValue, &topo)
-	} else {
-		err = fmt.Errorf("unrecognized topology file type: %s", topoPath)
+	if !hasAnySuffix(lowerTopoPath, []string{".yaml", ".yml", ".json"}) {
+		return nil, fmt.Errorf("unrecognized topology file type: %s", topoPath)
 	}
 
+	topoFile, err := os.Open(topoPath)
 	if err != nil {
 		return nil, err
 	}
+	defer topoFile.Close()
+
+	return io.ReadAll(topoFile)
+}
+
+// parseTopo parses a YAML or JSON topology. JSON is a subset of YAML, so both are
+// decoded with the YAML decoder; this keeps values such as durations ("10m") working
+// the same way in either format.
+func parseTopo(byteValue []byte) (*topology.File, error) {
+	var topo topology.File
+	err := yaml.Unmarshal(byteValue, &topo)
+	if err != nil {
+		return nil, err
+	}
+	if topo.Topology == nil {
+		return nil, fmt.Errorf("topology file is missing the topology section")
+	}
 	return &topo, nil
 }
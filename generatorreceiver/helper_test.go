@@ -0,0 +1,115 @@
+package generatorreceiver
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lightstep/telemetry-generator/generatorreceiver/internal/flags"
+	"github.com/lightstep/telemetry-generator/generatorreceiver/internal/topology"
+)
+
+const testTopoYaml = `
+topology:
+  services:
+    frontend:
+      routes:
+        /product:
+          maxLatencyMillis: 100
+flags:
+  - name: frontend_errors
+  - name: frontend_errors.phase_1
+    incident:
+      parentFlag: frontend_errors
+      start: 0m, 5m
+      duration: 2m
+rootRoutes:
+  - service: frontend
+    route: /product
+    tracesPerHour: 100
+`
+
+const testTopoJson = `{
+	"topology": {
+		"services": {
+			"frontend": {
+				"routes": {
+					"/product": {"maxLatencyMillis": 100}
+				}
+			}
+		}
+	},
+	"flags": [
+		{"name": "frontend_errors"},
+		{"name": "frontend_errors.phase_1", "incident": {"parentFlag": "frontend_errors", "start": "0m, 5m", "duration": "2m"}}
+	],
+	"rootRoutes": [
+		{"service": "frontend", "route": "/product", "tracesPerHour": 100}
+	]
+}`
+
+func TestParseTopoFile(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name     string
+		fileName string
+		content  string
+		error    bool
+	}{
+		{name: "yaml file", fileName: "topo.yaml", content: testTopoYaml},
+		{name: "yml file", fileName: "topo.yml", content: testTopoYaml},
+		{name: "json file", fileName: "topo.JSON", content: testTopoJson},
+		{name: "unknown extension", fileName: "topo.txt", content: testTopoYaml, error: true},
+		{name: "missing topology section", fileName: "empty.yaml", content: "rootRoutes: []", error: true},
+		{name: "malformed json", fileName: "bad.json", content: `{"topology": {`, error: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, tt.fileName)
+			require.NoError(t, os.WriteFile(path, []byte(tt.content), 0600))
+
+			topo, err := parseTopoFile(path)
+			if tt.error {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			requireTestTopo(t, topo)
+		})
+	}
+}
+
+func TestParseTopoFile_Stdin(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stdin")
+	require.NoError(t, os.WriteFile(path, []byte(testTopoJson), 0600))
+	stdin, err := os.Open(path)
+	require.NoError(t, err)
+	defer stdin.Close()
+
+	oldStdin := os.Stdin
+	os.Stdin = stdin
+	defer func() { os.Stdin = oldStdin }()
+
+	topo, err := parseTopoFile(stdinTopoPath)
+	require.NoError(t, err)
+	requireTestTopo(t, topo)
+}
+
+func TestParseTopo_Inline(t *testing.T) {
+	for _, inline := range []string{testTopoYaml, testTopoJson} {
+		topo, err := parseTopo([]byte(inline))
+		require.NoError(t, err)
+		requireTestTopo(t, topo)
+	}
+}
+
+func requireTestTopo(t *testing.T, topo *topology.File) {
+	require.NotNil(t, topo.Topology.GetServiceTier("frontend"))
+	require.Len(t, topo.Flags, 2)
+	require.Equal(t, flags.Start{0, 5 * time.Minute}, topo.Flags[1].Incident.Start)
+	require.Equal(t, 2*time.Minute, topo.Flags[1].Incident.Duration)
+	require.Equal(t, 100.0, topo.RootRoutes[0].TracesPerHour)
+}
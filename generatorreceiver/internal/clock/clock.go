@@ -0,0 +1,93 @@
+// Package clock is the source of the current time for generation. It is the wall clock unless
+// another clock is set: a simulated clock while backfilling or in tests, or a shifted or
+// accelerated clock.
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+type Clock interface {
+	Now() time.Time
+}
+
+type wallClock struct{}
+
+func (wallClock) Now() time.Time {
+	return time.Now()
+}
+
+var (
+	mu      sync.RWMutex
+	current Clock = wallClock{}
+)
+
+// Now returns the current time of the clock in use.
+func Now() time.Time {
+	mu.RLock()
+	defer mu.RUnlock()
+	return current.Now()
+}
+
+// Since returns the time elapsed since t on the clock in use.
+func Since(t time.Time) time.Duration {
+	return Now().Sub(t)
+}
+
+// Set makes c the clock in use, and returns a function that restores the previous one.
+func Set(c Clock) (restore func()) {
+	mu.Lock()
+	defer mu.Unlock()
+	previous := current
+	current = c
+	return func() {
+		mu.Lock()
+		defer mu.Unlock()
+		current = previous
+	}
+}
+
+// Scaled is a clock that starts at start and runs speed times as fast as the wall clock. With a
+// speed of 1 it is the wall clock shifted by an offset.
+type Scaled struct {
+	start     time.Time
+	wallStart time.Time
+	speed     float64
+}
+
+func NewScaled(start time.Time, speed float64) *Scaled {
+	return &Scaled{start: start, wallStart: time.Now(), speed: speed}
+}
+
+func (s *Scaled) Now() time.Time {
+	return s.start.Add(time.Duration(float64(time.Since(s.wallStart)) * s.speed))
+}
+
+// Simulated is a clock that only moves when it is set, so it is frozen in between.
+type Simulated struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func NewSimulated(now time.Time) *Simulated {
+	return &Simulated{now: now}
+}
+
+func (s *Simulated) Now() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.now
+}
+
+func (s *Simulated) Set(now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.now = now
+}
+
+func (s *Simulated) Advance(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.now = s.now.Add(d)
+}
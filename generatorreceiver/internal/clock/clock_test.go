@@ -0,0 +1,38 @@
+package clock
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSet(t *testing.T) {
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	simulated := NewSimulated(start)
+	restore := Set(simulated)
+	require.Equal(t, start, Now())
+
+	simulated.Advance(time.Minute)
+	require.Equal(t, start.Add(time.Minute), Now())
+	require.Equal(t, time.Hour, Since(start.Add(-59*time.Minute)))
+
+	restore()
+	require.WithinDuration(t, time.Now(), Now(), time.Second)
+}
+
+func TestScaled(t *testing.T) {
+	start := time.Now().Add(-24 * time.Hour)
+	wallStart := time.Now()
+	scaled := NewScaled(start, 3600)
+	time.Sleep(10 * time.Millisecond)
+	now := scaled.Now()
+	elapsed := time.Since(wallStart)
+
+	// at 3600x, every millisecond is 3.6s
+	require.False(t, now.Before(start.Add(36*time.Second)))
+	require.False(t, now.After(start.Add(elapsed*3600)))
+
+	offset := NewScaled(start, 1)
+	require.WithinDuration(t, start, offset.Now(), time.Second)
+}
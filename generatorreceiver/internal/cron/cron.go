@@ -1,27 +1,96 @@
 package cron
 
 import (
+	"github.com/lightstep/telemetry-generator/generatorreceiver/internal/clock"
 	"github.com/robfig/cron/v3"
 	"log"
 	"os"
+	"sort"
+	"sync"
+	"time"
 )
 
 var cronInstance *cron.Cron
 
+var (
+	pollingMu   sync.Mutex
+	stopPolling chan struct{}
+)
+
+type EntryID = cron.EntryID
+
 func init() {
 	cronInstance = cron.New(
 		cron.WithLogger(
 			cron.PrintfLogger(log.New(os.Stdout, "cron: ", log.LstdFlags))))
 }
 
-func Add(spec string, function func()) (cron.EntryID, error) {
+func Add(spec string, function func()) (EntryID, error) {
 	return cronInstance.AddFunc(spec, function)
 }
 
+func Remove(id EntryID) {
+	cronInstance.Remove(id)
+}
+
 func Start() {
 	cronInstance.Start()
 }
 
+// StartPolling runs the jobs when they are due on the clock in use rather than on the wall clock,
+// checking the schedules every interval, until Stop.
+func StartPolling(interval time.Duration) {
+	pollingMu.Lock()
+	defer pollingMu.Unlock()
+	if stopPolling != nil {
+		return
+	}
+	stop := make(chan struct{})
+	stopPolling = stop
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		last := clock.Now()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				now := clock.Now()
+				RunBetween(last, now)
+				last = now
+			}
+		}
+	}()
+}
+
 func Stop() {
 	cronInstance.Stop()
+
+	pollingMu.Lock()
+	defer pollingMu.Unlock()
+	if stopPolling != nil {
+		close(stopPolling)
+		stopPolling = nil
+	}
+}
+
+// RunBetween runs, in schedule order, the jobs scheduled after from and up to to. It is used
+// instead of Start when time is simulated.
+func RunBetween(from time.Time, to time.Time) {
+	type run struct {
+		at  time.Time
+		job cron.Job
+	}
+	var runs []run
+	for _, e := range cronInstance.Entries() {
+		for at := e.Schedule.Next(from); !at.IsZero() && !at.After(to); at = e.Schedule.Next(at) {
+			runs = append(runs, run{at: at, job: e.Job})
+		}
+	}
+	sort.SliceStable(runs, func(i, j int) bool { return runs[i].at.Before(runs[j].at) })
+	for _, r := range runs {
+		r.job.Run()
+	}
 }
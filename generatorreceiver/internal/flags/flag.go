@@ -2,6 +2,7 @@ package flags
 
 import (
 	"fmt"
+	"github.com/lightstep/telemetry-generator/generatorreceiver/internal/clock"
 	"github.com/lightstep/telemetry-generator/generatorreceiver/internal/cron"
 	"go.uber.org/zap"
 	"strings"
@@ -31,10 +32,12 @@ type FlagConfig struct {
 }
 
 type Flag struct {
-	cfg     FlagConfig
-	started time.Time
-	updated time.Time
-	mu      sync.Mutex
+	cfg         FlagConfig
+	started     time.Time
+	updated     time.Time
+	cronEntries []cron.EntryID
+	mu          sync.Mutex
+	manager     *FlagManager // where the parent flag is looked up, flags.Manager if nil
 }
 
 func NewFlag(cfg FlagConfig) Flag {
@@ -47,9 +50,30 @@ func (f *Flag) Name() string {
 
 func (f *Flag) Active() bool {
 	f.update()
+	f.mu.Lock()
+	defer f.mu.Unlock()
 	return f.active()
 }
 
+// state returns when the flag was started, zero if it is inactive, and last updated.
+func (f *Flag) state() (started, updated time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.started, f.updated
+}
+
+func (f *Flag) setState(started, updated time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.started, f.updated = started, updated
+}
+
+// Updated returns when the flag was last enabled or disabled.
+func (f *Flag) Updated() time.Time {
+	_, updated := f.state()
+	return updated
+}
+
 func (f *Flag) active() bool {
 	return !f.started.IsZero()
 }
@@ -62,13 +86,14 @@ func (f *Flag) update() {
 		return
 	}
 
-	f.mu.Lock()
-	defer f.mu.Unlock()
-
+	// the parent is looked up before locking, since the manager locks flags while holding its own lock
 	parent := f.parent() // won't be nil because we already validated all parents exist
 	incidentDuration := parent.CurrentDuration()
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
 	if f.active() != f.shouldBeActive(incidentDuration) {
-		f.Toggle()
+		f.toggle()
 	}
 }
 
@@ -87,31 +112,50 @@ func (f *Flag) shouldBeActive(incidentDuration time.Duration) bool {
 }
 
 func (f *Flag) CurrentDuration() time.Duration {
-	if !f.active() {
+	started, _ := f.state()
+	if started.IsZero() {
 		return 0
 	}
-	return time.Since(f.started)
+	return clock.Since(started)
 }
 
 func (f *Flag) Enable() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.enable()
+}
+
+func (f *Flag) Disable() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.disable()
+}
+
+func (f *Flag) Toggle() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.toggle()
+}
+
+func (f *Flag) enable() {
 	if !f.active() {
-		f.started = time.Now()
-		f.updated = time.Now()
+		f.started = clock.Now()
+		f.updated = clock.Now()
 	}
 }
 
-func (f *Flag) Disable() {
+func (f *Flag) disable() {
 	if f.active() {
 		f.started = time.Time{}
-		f.updated = time.Now()
+		f.updated = clock.Now()
 	}
 }
 
-func (f *Flag) Toggle() {
+func (f *Flag) toggle() {
 	if f.active() {
-		f.Disable()
+		f.disable()
 	} else {
-		f.Enable()
+		f.enable()
 	}
 }
 
@@ -123,23 +167,35 @@ func (f *Flag) Setup(logger *zap.Logger) {
 }
 
 func (f *Flag) SetupCron(logger *zap.Logger) {
-	_, err := cron.Add(f.cfg.Cron.Start, func() {
+	startEntry, err := cron.Add(f.cfg.Cron.Start, func() {
 		logger.Info("toggling flag on", zap.String("flag", f.cfg.Name))
 		f.Enable()
 	})
 	if err != nil {
 		logger.Error("error adding flag start schedule", zap.Error(err))
+	} else {
+		f.cronEntries = append(f.cronEntries, startEntry)
 	}
 
-	_, err = cron.Add(f.cfg.Cron.End, func() {
+	endEntry, err := cron.Add(f.cfg.Cron.End, func() {
 		logger.Info("toggling flag off", zap.String("flag", f.cfg.Name))
 		f.Disable()
 	})
 	if err != nil {
 		logger.Error("error adding flag stop schedule", zap.Error(err))
+	} else {
+		f.cronEntries = append(f.cronEntries, endEntry)
 	}
 }
 
+// Teardown removes the flag's cron schedules.
+func (f *Flag) Teardown() {
+	for _, entry := range f.cronEntries {
+		cron.Remove(entry)
+	}
+	f.cronEntries = nil
+}
+
 func (f *Flag) parentSpecified() bool {
 	return f.cfg.Incident != nil
 }
@@ -148,11 +204,14 @@ func (f *Flag) parent() *Flag {
 	if !f.parentSpecified() {
 		return nil
 	}
-	return Manager.GetFlag(f.cfg.Incident.ParentFlag)
+	if f.manager == nil {
+		return Manager.GetFlag(f.cfg.Incident.ParentFlag)
+	}
+	return f.manager.GetFlag(f.cfg.Incident.ParentFlag)
 }
 
-func (ic IncidentConfig) validate() error {
-	if Manager.GetFlag(ic.ParentFlag) == nil {
+func (ic IncidentConfig) validate(fm *FlagManager) error {
+	if fm.GetFlag(ic.ParentFlag) == nil {
 		return fmt.Errorf("parent flag %s does not exist", ic.ParentFlag)
 	}
 	if len(ic.Start) == 0 {
@@ -12,13 +12,14 @@ type EmbeddedFlags struct {
 
 func (f EmbeddedFlags) ShouldGenerate() bool {
 	// TODO: use the set flag's _value_... somehow
+	// flags that do not exist (e.g. while a new topology is being validated) count as inactive
 	if f.FlagSet != "" {
-		if set := Manager.GetFlag(f.FlagSet); !set.Active() {
+		if set := Manager.GetFlag(f.FlagSet); set == nil || !set.Active() {
 			return false
 		}
 	}
 	if f.FlagUnset != "" {
-		if unset := Manager.GetFlag(f.FlagUnset); unset.Active() {
+		if unset := Manager.GetFlag(f.FlagUnset); unset != nil && unset.Active() {
 			return false
 		}
 	}
@@ -36,12 +37,12 @@ func (f EmbeddedFlags) GenerateStartTime() time.Time {
 
 	s, u := time.UnixMilli(0), time.UnixMilli(0)
 
-	if f.FlagSet != "" {
-		s = Manager.GetFlag(f.FlagSet).updated
+	if set := Manager.GetFlag(f.FlagSet); set != nil {
+		s = set.Updated()
 	}
 
-	if f.FlagUnset != "" {
-		u = Manager.GetFlag(f.FlagUnset).updated
+	if unset := Manager.GetFlag(f.FlagUnset); unset != nil {
+		u = unset.Updated()
 	}
 
 	if s.After(u) {
@@ -51,11 +52,12 @@ func (f EmbeddedFlags) GenerateStartTime() time.Time {
 	return u
 }
 
-func (f EmbeddedFlags) ValidateFlags() error {
-	if f.FlagSet != "" && Manager.GetFlag(f.FlagSet) == nil {
+// ValidateFlags checks that the flags exist in fm.
+func (f EmbeddedFlags) ValidateFlags(fm *FlagManager) error {
+	if f.FlagSet != "" && fm.GetFlag(f.FlagSet) == nil {
 		return fmt.Errorf("flag %v does not exist", f.FlagSet)
 	}
-	if f.FlagUnset != "" && Manager.GetFlag(f.FlagUnset) == nil {
+	if f.FlagUnset != "" && fm.GetFlag(f.FlagUnset) == nil {
 		return fmt.Errorf("flag %v does not exist", f.FlagUnset)
 	}
 	return nil
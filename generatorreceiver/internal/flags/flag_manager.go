@@ -18,8 +18,21 @@ func init() {
 	Manager = NewFlagManager()
 }
 
-func NewFlagManager() *FlagManager {
-	return &FlagManager{flags: make(map[string]*Flag)}
+// NewFlagManager returns a manager with configFlags loaded. The flags are not set up, so their
+// cron schedules do not run; this is what validating a topology against its own flags needs.
+func NewFlagManager(configFlags ...FlagConfig) *FlagManager {
+	fm := &FlagManager{flags: make(map[string]*Flag, len(configFlags))}
+	for _, cfg := range configFlags {
+		fm.flags[cfg.Name] = fm.newFlag(cfg)
+	}
+	return fm
+}
+
+// newFlag returns a flag that looks up its parent in fm.
+func (fm *FlagManager) newFlag(cfg FlagConfig) *Flag {
+	flag := NewFlag(cfg)
+	flag.manager = fm
+	return &flag
 }
 
 func (fm *FlagManager) Clear() {
@@ -39,12 +52,46 @@ func (fm *FlagManager) LoadFlags(configFlags []FlagConfig, logger *zap.Logger) {
 	defer fm.mu.Unlock()
 
 	for _, cfg := range configFlags {
-		flag := NewFlag(cfg)
+		flag := fm.newFlag(cfg)
 		flag.Setup(logger)
-		fm.flags[flag.Name()] = &flag
+		fm.flags[flag.Name()] = flag
 	}
 }
 
+// ReloadFlags replaces the loaded flags with configFlags. validate is called first with a
+// separate manager holding configFlags, so that the loaded flags are left untouched if it fails,
+// in which case its error is returned. Flags that keep their name also keep their state.
+func (fm *FlagManager) ReloadFlags(configFlags []FlagConfig, logger *zap.Logger, validate func(*FlagManager) error) error {
+	if err := validate(NewFlagManager(configFlags...)); err != nil {
+		return err
+	}
+
+	fm.mu.Lock()
+	previous := fm.flags
+	reloaded := make(map[string]*Flag, len(configFlags))
+	for _, cfg := range configFlags {
+		flag := fm.newFlag(cfg)
+		if old, ok := previous[cfg.Name]; ok {
+			// the schedules are removed before the state is copied, so that no toggle is lost
+			old.Teardown()
+			flag.setState(old.state())
+		}
+		reloaded[cfg.Name] = flag
+	}
+	fm.flags = reloaded
+	fm.mu.Unlock()
+
+	for name, f := range previous {
+		if _, ok := reloaded[name]; !ok {
+			f.Teardown()
+		}
+	}
+	for _, f := range reloaded {
+		f.Setup(logger)
+	}
+	return nil
+}
+
 func (fm *FlagManager) ValidateFlags() error {
 	validatedFlags := make(map[string]bool)
 	for _, f := range fm.GetFlags() {
@@ -61,6 +108,16 @@ func (fm *FlagManager) ValidateFlags() error {
 	return nil
 }
 
+// ValidateFlag validates the flag named name and the chain of its parent flags.
+func (fm *FlagManager) ValidateFlag(name string) error {
+	f := fm.GetFlag(name)
+	if f == nil {
+		return fmt.Errorf("flag %s does not exist", name)
+	}
+	_, err := fm.traverseFlagGraph(f)
+	return err
+}
+
 func (fm *FlagManager) traverseFlagGraph(f *Flag) (map[string]bool, error) {
 	seenFlags := make(map[string]bool)
 	var orderedFlags []string // needed for printing flags in-order if cycle is detected
@@ -71,12 +128,12 @@ func (fm *FlagManager) traverseFlagGraph(f *Flag) (map[string]bool, error) {
 		if !f.parentSpecified() { // no parent specified -> this is a root flag, so we've traversed graph without finding cycle
 			return seenFlags, nil
 		}
-		err := f.cfg.Incident.validate() // this is a child flag, so check that its incident config is valid
+		err := f.cfg.Incident.validate(fm) // this is a child flag, so check that its incident config is valid
 		if err != nil {
 			return nil, fmt.Errorf("error with flag %s: %v", f.Name(), err)
 		}
 
-		f = f.parent()
+		f = fm.GetFlag(f.cfg.Incident.ParentFlag)
 	}
 	return nil, fmt.Errorf("cyclical flag graph detected: %s", printFlagCycle(orderedFlags, f.Name()))
 }
@@ -99,3 +156,22 @@ func (fm *FlagManager) GetFlag(name string) *Flag {
 	defer fm.mu.Unlock()
 	return fm.flags[name]
 }
+
+// ActiveCount returns the number of flags that are currently active.
+func (fm *FlagManager) ActiveCount() int {
+	fm.mu.Lock()
+	flags := make([]*Flag, 0, len(fm.flags))
+	for _, f := range fm.flags {
+		flags = append(flags, f)
+	}
+	fm.mu.Unlock()
+
+	// Active looks up parent flags, so the lock must be released first
+	count := 0
+	for _, f := range flags {
+		if f.Active() {
+			count++
+		}
+	}
+	return count
+}
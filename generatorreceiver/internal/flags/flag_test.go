@@ -2,6 +2,7 @@ package flags
 
 import (
 	"fmt"
+	"github.com/lightstep/telemetry-generator/generatorreceiver/internal/clock"
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/zap"
 	"testing"
@@ -112,7 +113,7 @@ func TestEmbeddedFlags_Validate(t *testing.T) {
 			}
 			Manager.LoadFlags(theFlags, zap.NewNop())
 
-			err := tt.embeddedFlags.ValidateFlags()
+			err := tt.embeddedFlags.ValidateFlags(Manager)
 			if err != nil && !tt.error {
 				assert.Fail(t, fmt.Sprintf("did not expect validation error but got: %v", err))
 			}
@@ -125,6 +126,8 @@ func TestEmbeddedFlags_Validate(t *testing.T) {
 
 func TestEmbeddedFlags_GenerateTime(t *testing.T) {
 	Manager.Clear()
+	simulated := clock.NewSimulated(time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC))
+	defer clock.Set(simulated)()
 
 	Manager.LoadFlags([]FlagConfig{
 		{
@@ -162,7 +165,7 @@ func TestEmbeddedFlags_GenerateTime(t *testing.T) {
 	}
 
 	a.Enable()
-	time.Sleep(10 * time.Millisecond)
+	simulated.Advance(10 * time.Millisecond)
 	b.Disable()
 	if st := ef.GenerateStartTime().UnixNano(); st != b.updated.UnixNano() {
 		assert.Fail(t, "start time should be the same as the time that the last flag was set.")
@@ -172,7 +175,7 @@ func TestEmbeddedFlags_GenerateTime(t *testing.T) {
 	b.Enable()
 
 	b.Disable()
-	time.Sleep(10 * time.Millisecond)
+	simulated.Advance(10 * time.Millisecond)
 	a.Enable()
 	if st := ef.GenerateStartTime().UnixNano(); st != a.updated.UnixNano() {
 		assert.Fail(t, "start time should be the same as the time that the last flag was set.")
@@ -296,7 +299,7 @@ func TestIncidentConfig_validate(t *testing.T) {
 				Duration:   parsedDuration,
 			}
 
-			err := incidentCfg.validate()
+			err := incidentCfg.validate(Manager)
 			if err != nil && !tt.error {
 				assert.Fail(t, fmt.Sprintf("did not expect validation error but got: %v", err))
 			}
@@ -400,3 +403,49 @@ func TestFlag_shouldBeActive(t *testing.T) {
 		})
 	}
 }
+
+func TestFlag_IncidentOnSimulatedClock(t *testing.T) {
+	Manager.Clear()
+	simulated := clock.NewSimulated(time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC))
+	defer clock.Set(simulated)()
+
+	Manager.LoadFlags([]FlagConfig{
+		{Name: "incident"},
+		{Name: "incident.phase_1", Incident: &IncidentConfig{ParentFlag: "incident", Start: Start{5 * time.Minute}, Duration: 10 * time.Minute}},
+	}, zap.NewNop())
+	parent := Manager.GetFlag("incident")
+	child := Manager.GetFlag("incident.phase_1")
+
+	parent.Enable()
+	assert.False(t, child.Active())
+
+	simulated.Advance(6 * time.Minute)
+	assert.Equal(t, 6*time.Minute, parent.CurrentDuration())
+	assert.True(t, child.Active())
+
+	simulated.Advance(10 * time.Minute)
+	assert.False(t, child.Active())
+}
+
+func TestFlagManager_ReloadFlags(t *testing.T) {
+	Manager.Clear()
+	Manager.LoadFlags([]FlagConfig{{Name: "flag_a"}, {Name: "flag_b"}}, zap.NewNop())
+	Manager.GetFlag("flag_a").Enable()
+	loaded := Manager.GetFlags()
+
+	// a rejected reload leaves the loaded flags untouched, even while it is being validated
+	err := Manager.ReloadFlags([]FlagConfig{{Name: "flag_a"}, {Name: "flag_c"}}, zap.NewNop(), func(fm *FlagManager) error {
+		assert.NotNil(t, fm.GetFlag("flag_c"))
+		assert.Nil(t, Manager.GetFlag("flag_c"))
+		return fmt.Errorf("invalid")
+	})
+	assert.Error(t, err)
+	assert.Equal(t, loaded, Manager.GetFlags())
+
+	// flags that keep their name keep their state
+	err = Manager.ReloadFlags([]FlagConfig{{Name: "flag_a"}, {Name: "flag_c"}}, zap.NewNop(), func(*FlagManager) error { return nil })
+	assert.NoError(t, err)
+	assert.True(t, Manager.GetFlag("flag_a").Active())
+	assert.False(t, Manager.GetFlag("flag_c").Active())
+	assert.Nil(t, Manager.GetFlag("flag_b"))
+}
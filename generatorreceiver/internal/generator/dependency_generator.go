@@ -0,0 +1,51 @@
+package generator
+
+import (
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+
+	"github.com/lightstep/telemetry-generator/generatorreceiver/internal/topology"
+)
+
+// createDependencySpan creates the CLIENT span of a call to a dependency in the calling service.
+// Dependencies have no spans of their own.
+func (g *TraceGenerator) createDependencySpan(spans ptrace.SpanSlice, d *topology.Dependency, c topology.Call, startTimeNanos int64, traceId pcommon.TraceID, parentSpanId pcommon.SpanID) ptrace.Span {
+	span := spans.AppendEmpty()
+	if c.Route != "" {
+		span.SetName(c.Route)
+	} else {
+		span.SetName(d.Name)
+	}
+	span.SetTraceID(traceId)
+	span.SetParentSpanID(parentSpanId)
+	span.SetSpanID(g.genSpanId())
+	span.SetKind(ptrace.SpanKindClient)
+
+	attr := span.Attributes()
+	attr.PutStr(string(semconv.PeerServiceKey), d.Name)
+	attr.PutStr(string(semconv.NetPeerNameKey), d.GetHost())
+	if d.IsHTTP() {
+		attr.PutStr(string(semconv.HTTPMethodKey), d.GetMethod())
+		attr.PutStr(string(semconv.HTTPURLKey), d.RenderURL(c.Route, traceId.String(), span.SpanID().String()))
+	} else {
+		attr.PutStr(string(semconv.DBSystemKey), d.System)
+		if d.DBName != "" {
+			attr.PutStr(string(semconv.DBNameKey), d.DBName)
+		}
+		if statement := d.RenderStatement(g.random, c.Route, traceId.String(), span.SpanID().String()); statement != "" {
+			attr.PutStr(string(semconv.DBStatementKey), statement)
+		}
+	}
+	d.Attributes.InsertTags(&attr, g.random)
+
+	endTimeNanos := startTimeNanos + c.SampleNetworkLatency(g.random) + d.SampleLatency(traceId, g.random) + c.SampleNetworkLatency(g.random)
+	span.SetStartTimestamp(pcommon.NewTimestampFromTime(time.Unix(0, startTimeNanos)))
+	span.SetEndTimestamp(pcommon.NewTimestampFromTime(time.Unix(0, endTimeNanos)))
+	if e := d.SampleError(g.random); e != nil {
+		setSpanError(span, e, d.GetHost(), c.Route)
+	}
+	return span
+}
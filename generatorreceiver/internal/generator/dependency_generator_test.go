@@ -0,0 +1,95 @@
+package generator
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+
+	"github.com/lightstep/telemetry-generator/generatorreceiver/internal/flags"
+	"github.com/lightstep/telemetry-generator/generatorreceiver/internal/topology"
+)
+
+func TestTraceGenerator_Dependencies(t *testing.T) {
+	flags.Manager.Clear()
+	topo := &topology.Topology{
+		Services: map[string]*topology.ServiceTier{
+			"checkoutservice": {
+				ServiceName: "checkoutservice",
+				Routes: map[string]*topology.ServiceRoute{
+					"/PlaceOrder": {
+						DownstreamCalls: []topology.Call{
+							{Service: "postgres", Route: "INSERT orders"},
+							{Service: "stripe", Route: "/v1/charges", OnError: topology.SwallowErrors},
+						},
+						MaxLatencyMillis: 5,
+					},
+				},
+			},
+		},
+		Dependencies: map[string]*topology.Dependency{
+			"postgres": {
+				Name:             "postgres",
+				Type:             topology.DatabaseDependency,
+				System:           "postgresql",
+				Host:             "orders-db.internal",
+				DBName:           "orders",
+				Statements:       []string{"INSERT INTO orders VALUES ($1, $2) -- $route"},
+				MaxLatencyMillis: 20,
+			},
+			"stripe": {
+				Name:             "stripe",
+				Type:             topology.HTTPDependency,
+				Method:           "POST",
+				URL:              "https://api.stripe.com$route",
+				MaxLatencyMillis: 300,
+				Errors:           []topology.ErrorConfig{{StatusCode: 429}},
+				Attributes:       topology.TagMap{"vendor": "stripe"},
+			},
+		},
+	}
+
+	traces := NewTraceGenerator(topo, rand.New(rand.NewSource(123)), "checkoutservice", "/PlaceOrder").Generate(1000)
+
+	// only the calling service has spans
+	require.Equal(t, 1, traces.ResourceSpans().Len())
+	spans := traces.ResourceSpans().At(0).ScopeSpans().At(0).Spans()
+	require.Equal(t, 3, spans.Len())
+	root, db, http := spans.At(0), spans.At(1), spans.At(2)
+
+	expected := map[ptrace.Span]map[string]string{
+		db: {
+			"peer.service":  "postgres",
+			"net.peer.name": "orders-db.internal",
+			"db.system":     "postgresql",
+			"db.name":       "orders",
+			"db.statement":  "INSERT INTO orders VALUES ($1, $2) -- INSERT orders",
+		},
+		http: {
+			"peer.service":  "stripe",
+			"net.peer.name": "stripe",
+			"http.method":   "POST",
+			"http.url":      "https://api.stripe.com/v1/charges",
+			"vendor":        "stripe",
+		},
+	}
+	for span, attrs := range expected {
+		require.Equal(t, ptrace.SpanKindClient, span.Kind())
+		require.Equal(t, root.SpanID(), span.ParentSpanID())
+		require.Less(t, span.StartTimestamp(), span.EndTimestamp())
+		require.LessOrEqual(t, span.EndTimestamp(), root.EndTimestamp())
+		for k, v := range attrs {
+			val, ok := span.Attributes().Get(k)
+			require.True(t, ok, k)
+			require.Equal(t, v, val.AsString())
+		}
+	}
+	require.Equal(t, "INSERT orders", db.Name())
+
+	// the dependency's errors fail the CLIENT span, and follow the call's onError
+	require.Equal(t, ptrace.StatusCodeError, http.Status().Code())
+	statusCode, _ := http.Attributes().Get("http.status_code")
+	require.Equal(t, int64(429), statusCode.Int())
+	require.Equal(t, ptrace.StatusCodeUnset, root.Status().Code())
+}
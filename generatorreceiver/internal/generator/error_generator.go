@@ -0,0 +1,50 @@
+package generator
+
+import (
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+
+	"github.com/lightstep/telemetry-generator/generatorreceiver/internal/topology"
+)
+
+// setSpanError fails the span with e, recording e's exception as an exception event at the
+// end of the span.
+func setSpanError(span ptrace.Span, e *topology.ErrorConfig, serviceName string, routeName string) {
+	traceID, spanID := span.TraceID().String(), span.SpanID().String()
+
+	span.Status().SetCode(ptrace.StatusCodeError)
+	span.Status().SetMessage(e.RenderMessage(serviceName, routeName, traceID, spanID))
+	if e.StatusCode != 0 {
+		span.Attributes().PutInt(string(semconv.HTTPStatusCodeKey), int64(e.StatusCode))
+	}
+
+	if e.Exception == nil {
+		return
+	}
+	event := span.Events().AppendEmpty()
+	event.SetName(semconv.ExceptionEventName)
+	event.SetTimestamp(span.EndTimestamp())
+	attrs := event.Attributes()
+	attrs.PutStr(string(semconv.ExceptionTypeKey), e.Exception.Type)
+	if e.Exception.Message != "" {
+		attrs.PutStr(string(semconv.ExceptionMessageKey), e.Exception.RenderMessage(serviceName, routeName, traceID, spanID))
+	}
+	if e.Exception.Stacktrace != "" {
+		attrs.PutStr(string(semconv.ExceptionStacktraceKey), e.Exception.RenderStacktrace(serviceName, routeName, traceID, spanID))
+	}
+}
+
+// propagateError copies the error attribute and an error status from a callee's span to its caller's.
+func propagateError(from ptrace.Span, to ptrace.Span) {
+	if val, ok := from.Attributes().Get("error"); ok {
+		val.CopyTo(to.Attributes().PutEmpty("error"))
+	}
+	if spanFailed(from) && !spanFailed(to) {
+		to.Status().SetCode(ptrace.StatusCodeError)
+		to.Status().SetMessage(from.Status().Message())
+	}
+}
+
+func spanFailed(span ptrace.Span) bool {
+	return span.Status().Code() == ptrace.StatusCodeError
+}
@@ -0,0 +1,138 @@
+package generator
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.uber.org/zap"
+
+	"github.com/lightstep/telemetry-generator/generatorreceiver/internal/flags"
+	"github.com/lightstep/telemetry-generator/generatorreceiver/internal/topology"
+)
+
+func errorTestTopology(onError string) *topology.Topology {
+	return &topology.Topology{
+		Services: map[string]*topology.ServiceTier{
+			"frontend": {
+				ServiceName: "frontend",
+				Routes: map[string]*topology.ServiceRoute{
+					"/checkout": {
+						DownstreamCalls:  []topology.Call{{Service: "paymentservice", Route: "/Charge", OnError: onError, Retries: 2}},
+						MaxLatencyMillis: 5,
+					},
+				},
+			},
+			"paymentservice": {
+				ServiceName: "paymentservice",
+				Routes: map[string]*topology.ServiceRoute{
+					"/Charge": {
+						MaxLatencyMillis: 10,
+						Errors: []topology.ErrorConfig{{
+							StatusCode: 503,
+							Message:    "charge failed in $service",
+							Exception: &topology.Exception{
+								Type:       "PaymentDeclined",
+								Message:    "card declined on $route",
+								Stacktrace: "at $service.Charge",
+							},
+						}},
+					},
+				},
+			},
+		},
+	}
+}
+
+func generateErrorTestTrace(topo *topology.Topology) (root ptrace.Span, clients ptrace.SpanSlice, servers []ptrace.Span) {
+	traces := ptrace.NewTraces()
+	g := NewTraceGenerator(topo, rand.New(rand.NewSource(123)), "frontend", "/checkout")
+	root = *g.createSpanForServiceRouteCall(&traces, g.service, g.route, 1000, g.genTraceId(), pcommon.NewSpanIDEmpty())
+
+	clients = ptrace.NewSpanSlice()
+	for i := 1; i < traces.ResourceSpans().Len(); i++ {
+		servers = append(servers, traces.ResourceSpans().At(i).ScopeSpans().At(0).Spans().At(0))
+	}
+	rootSpans := traces.ResourceSpans().At(0).ScopeSpans().At(0).Spans()
+	for i := 1; i < rootSpans.Len(); i++ {
+		rootSpans.At(i).CopyTo(clients.AppendEmpty())
+	}
+	return root, clients, servers
+}
+
+func TestTraceGenerator_Errors(t *testing.T) {
+	flags.Manager.Clear()
+	tests := []struct {
+		name       string
+		onError    string
+		attempts   int
+		rootFailed bool
+	}{
+		{name: "propagate", onError: topology.PropagateErrors, attempts: 1, rootFailed: true},
+		{name: "propagate by default", attempts: 1, rootFailed: true},
+		{name: "retry", onError: topology.RetryErrors, attempts: 3, rootFailed: true},
+		{name: "swallow", onError: topology.SwallowErrors, attempts: 1, rootFailed: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root, clients, servers := generateErrorTestTrace(errorTestTopology(tt.onError))
+
+			require.Equal(t, tt.attempts, clients.Len())
+			require.Len(t, servers, tt.attempts)
+			for i := 0; i < clients.Len(); i++ {
+				require.Equal(t, ptrace.StatusCodeError, clients.At(i).Status().Code())
+				require.Equal(t, ptrace.StatusCodeError, servers[i].Status().Code())
+				if i > 0 {
+					// retries start once the failed attempt has returned
+					require.Equal(t, clients.At(i-1).EndTimestamp(), clients.At(i).StartTimestamp())
+				}
+			}
+			if tt.rootFailed {
+				require.Equal(t, ptrace.StatusCodeError, root.Status().Code())
+				require.Equal(t, "charge failed in paymentservice", root.Status().Message())
+			} else {
+				require.Equal(t, ptrace.StatusCodeUnset, root.Status().Code())
+			}
+
+			server := servers[0]
+			require.Equal(t, "charge failed in paymentservice", server.Status().Message())
+			statusCode, ok := server.Attributes().Get("http.status_code")
+			require.True(t, ok)
+			require.Equal(t, int64(503), statusCode.Int())
+
+			require.Equal(t, 1, server.Events().Len())
+			event := server.Events().At(0)
+			require.Equal(t, "exception", event.Name())
+			require.Equal(t, server.EndTimestamp(), event.Timestamp())
+			expected := map[string]string{
+				"exception.type":       "PaymentDeclined",
+				"exception.message":    "card declined on /Charge",
+				"exception.stacktrace": "at paymentservice.Charge",
+			}
+			for k, v := range expected {
+				val, ok := event.Attributes().Get(k)
+				require.True(t, ok, k)
+				require.Equal(t, v, val.AsString())
+			}
+		})
+	}
+}
+
+func TestTraceGenerator_FlagGatedErrors(t *testing.T) {
+	flags.Manager.Clear()
+	flags.Manager.LoadFlags([]flags.FlagConfig{{Name: "payment_errors"}}, zap.NewNop())
+	topo := errorTestTopology(topology.PropagateErrors)
+	topo.GetServiceTier("paymentservice").GetRoute("/Charge").Errors[0].FlagSet = "payment_errors"
+
+	root, _, servers := generateErrorTestTrace(topo)
+	require.Equal(t, ptrace.StatusCodeUnset, root.Status().Code())
+	require.Equal(t, ptrace.StatusCodeUnset, servers[0].Status().Code())
+	require.Equal(t, 0, servers[0].Events().Len())
+
+	flags.Manager.GetFlag("payment_errors").Enable()
+	root, _, servers = generateErrorTestTrace(topo)
+	require.Equal(t, ptrace.StatusCodeError, root.Status().Code())
+	require.Equal(t, ptrace.StatusCodeError, servers[0].Status().Code())
+}
@@ -0,0 +1,60 @@
+package generator
+
+import (
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+
+	"github.com/lightstep/telemetry-generator/generatorreceiver/internal/topology"
+)
+
+// appendLogs adds the log records configured for the span's service and route to g.logs,
+// correlated with the span and sharing the span's resource.
+func (g *TraceGenerator) appendLogs(resource pcommon.Resource, serviceTier *topology.ServiceTier, route *topology.ServiceRoute, span ptrace.Span) {
+	if g.logs == nil || (len(serviceTier.Logs) == 0 && len(route.Logs) == 0) {
+		return
+	}
+
+	records := plog.NewLogRecordSlice()
+	for _, logs := range [][]topology.Log{serviceTier.Logs, route.Logs} {
+		for i := range logs {
+			l := &logs[i]
+			if !l.ShouldGenerate() {
+				continue
+			}
+			for n := l.SampleCount(g.random); n > 0; n-- {
+				g.appendLogRecord(records, l, serviceTier.ServiceName, route.Route, span)
+			}
+		}
+	}
+
+	if records.Len() == 0 {
+		return
+	}
+
+	rlogs := g.logs.ResourceLogs().AppendEmpty()
+	resource.CopyTo(rlogs.Resource())
+	records.MoveAndAppendTo(rlogs.ScopeLogs().AppendEmpty().LogRecords())
+}
+
+func (g *TraceGenerator) appendLogRecord(records plog.LogRecordSlice, l *topology.Log, serviceName string, routeName string, span ptrace.Span) {
+	start, end := int64(span.StartTimestamp()), int64(span.EndTimestamp())
+	timestamp := start
+	if end > start {
+		timestamp += g.random.Int63n(end - start)
+	}
+
+	record := records.AppendEmpty()
+	record.SetTimestamp(pcommon.NewTimestampFromTime(time.Unix(0, timestamp)))
+	record.SetObservedTimestamp(record.Timestamp())
+	record.SetTraceID(span.TraceID())
+	record.SetSpanID(span.SpanID())
+	record.SetSeverityNumber(l.Severity())
+	record.SetSeverityText(l.SeverityText())
+	record.Body().SetStr(l.RenderBody(g.random, serviceName, routeName, span.TraceID().String(), span.SpanID().String()))
+
+	attrs := record.Attributes()
+	l.Attributes.InsertTags(&attrs, g.random)
+}
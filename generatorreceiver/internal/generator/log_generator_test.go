@@ -0,0 +1,88 @@
+package generator
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/plog"
+
+	"github.com/lightstep/telemetry-generator/generatorreceiver/internal/flags"
+	"github.com/lightstep/telemetry-generator/generatorreceiver/internal/topology"
+)
+
+func TestTraceGenerator_GenerateWithLogs(t *testing.T) {
+	flags.Manager.Clear()
+	topo := &topology.Topology{
+		Services: map[string]*topology.ServiceTier{
+			"frontend": {
+				Routes: map[string]*topology.ServiceRoute{
+					"/cart": {
+						DownstreamCalls:  []topology.Call{{Service: "cartservice", Route: "/GetCart"}},
+						MaxLatencyMillis: 20,
+						Logs: []topology.Log{
+							{Level: "info", Bodies: []string{"rendering $route"}, Rate: 2},
+						},
+					},
+				},
+				ResourceAttributeSets: []topology.ResourceAttributeSet{
+					{ResourceAttributes: topology.TagMap{"cloud.region": "us-east-1"}},
+				},
+			},
+			"cartservice": {
+				Routes: map[string]*topology.ServiceRoute{
+					"/GetCart": {MaxLatencyMillis: 10},
+				},
+				Logs: []topology.Log{
+					{Level: "error", Bodies: []string{"$service failed"}},
+				},
+			},
+		},
+	}
+	require.NoError(t, topo.Load())
+
+	g := NewTraceGenerator(topo, rand.New(rand.NewSource(123)), "frontend", "/cart")
+	traces, logs := g.GenerateWithLogs(123)
+
+	require.Equal(t, 3, logs.LogRecordCount())
+	require.Equal(t, 2, logs.ResourceLogs().Len())
+	require.Nil(t, g.logs)
+
+	spans := traces.ResourceSpans()
+	for i := 0; i < logs.ResourceLogs().Len(); i++ {
+		rl := logs.ResourceLogs().At(i)
+		serviceName, ok := rl.Resource().Attributes().Get("service.name")
+		require.True(t, ok)
+
+		records := rl.ScopeLogs().At(0).LogRecords()
+		for j := 0; j < records.Len(); j++ {
+			record := records.At(j)
+			found := false
+			for k := 0; k < spans.Len(); k++ {
+				span := spans.At(k).ScopeSpans().At(0).Spans().At(0)
+				if span.SpanID() != record.SpanID() {
+					continue
+				}
+				found = true
+				require.Equal(t, span.TraceID(), record.TraceID())
+				require.GreaterOrEqual(t, record.Timestamp(), span.StartTimestamp())
+				require.LessOrEqual(t, record.Timestamp(), span.EndTimestamp())
+				spanServiceName, _ := spans.At(k).Resource().Attributes().Get("service.name")
+				require.Equal(t, spanServiceName.AsString(), serviceName.AsString())
+			}
+			require.True(t, found)
+
+			switch serviceName.AsString() {
+			case "frontend":
+				require.Equal(t, plog.SeverityNumberInfo, record.SeverityNumber())
+				require.Equal(t, "rendering /cart", record.Body().AsString())
+				region, ok := rl.Resource().Attributes().Get("cloud.region")
+				require.True(t, ok)
+				require.Equal(t, "us-east-1", region.AsString())
+			case "cartservice":
+				require.Equal(t, plog.SeverityNumberError, record.SeverityNumber())
+				require.Equal(t, "cartservice failed", record.Body().AsString())
+			}
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package generator
+
+import (
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+
+	"github.com/lightstep/telemetry-generator/generatorreceiver/internal/topology"
+)
+
+// createSpansForAsyncCall creates the PRODUCER span of an async call in the calling service and the
+// callee's CONSUMER span, which starts after the queue delay, and returns the PRODUCER span.
+func (g *TraceGenerator) createSpansForAsyncCall(traces *ptrace.Traces, spans ptrace.SpanSlice, c topology.Call, startTimeNanos int64, traceId pcommon.TraceID, parentSpanId pcommon.SpanID) ptrace.Span {
+	destination := c.Async.GetDestination(c.Service)
+
+	producerSpan := spans.AppendEmpty()
+	producerSpan.SetName(fmt.Sprintf("%s send", destination))
+	producerSpan.SetTraceID(traceId)
+	producerSpan.SetParentSpanID(parentSpanId)
+	producerSpan.SetSpanID(g.genSpanId())
+	producerSpan.SetKind(ptrace.SpanKindProducer)
+	putMessagingAttributes(producerSpan, c)
+	producerSpan.Attributes().PutStr(string(semconv.PeerServiceKey), c.Service)
+
+	endTimeNanos := startTimeNanos + c.SampleNetworkLatency(g.random)
+	producerSpan.SetStartTimestamp(pcommon.NewTimestampFromTime(time.Unix(0, startTimeNanos)))
+	producerSpan.SetEndTimestamp(pcommon.NewTimestampFromTime(time.Unix(0, endTimeNanos)))
+
+	consumerStartTimeNanos := endTimeNanos + c.Async.SampleQueueDelay(g.random)
+	consumerTraceId, consumerParentSpanId := traceId, producerSpan.SpanID()
+	if c.Async.NewTrace {
+		consumerTraceId, consumerParentSpanId = g.genTraceId(), pcommon.NewSpanIDEmpty()
+	}
+	consumerSpan := g.createSpanForServiceRouteCall(traces, c.Service, c.Route, consumerStartTimeNanos, consumerTraceId, consumerParentSpanId)
+	consumerSpan.SetKind(ptrace.SpanKindConsumer)
+	putMessagingAttributes(*consumerSpan, c)
+	consumerSpan.Attributes().PutStr(string(semconv.MessagingOperationKey), semconv.MessagingOperationProcess.Value.AsString())
+	if c.Async.GetSystem() == topology.DefaultMessagingSystem {
+		consumerSpan.Attributes().PutStr(string(semconv.MessagingKafkaConsumerGroupKey), c.Service)
+	}
+	if c.Async.NewTrace {
+		link := consumerSpan.Links().AppendEmpty()
+		link.SetTraceID(traceId)
+		link.SetSpanID(producerSpan.SpanID())
+	}
+
+	return producerSpan
+}
+
+func putMessagingAttributes(span ptrace.Span, c topology.Call) {
+	attr := span.Attributes()
+	attr.PutStr(string(semconv.MessagingSystemKey), c.Async.GetSystem())
+	attr.PutStr(string(semconv.MessagingDestinationKey), c.Async.GetDestination(c.Service))
+	attr.PutStr(string(semconv.MessagingDestinationKindKey), c.Async.GetDestinationKind())
+}
@@ -0,0 +1,93 @@
+package generator
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+
+	"github.com/lightstep/telemetry-generator/generatorreceiver/internal/flags"
+	"github.com/lightstep/telemetry-generator/generatorreceiver/internal/topology"
+)
+
+func TestTraceGenerator_AsyncCalls(t *testing.T) {
+	flags.Manager.Clear()
+	for _, newTrace := range []bool{false, true} {
+		topo := &topology.Topology{
+			Services: map[string]*topology.ServiceTier{
+				"checkoutservice": {
+					ServiceName: "checkoutservice",
+					Routes: map[string]*topology.ServiceRoute{
+						"/PlaceOrder": {
+							DownstreamCalls: []topology.Call{{
+								Service: "emailservice",
+								Route:   "/SendOrderConfirmation",
+								Async:   &topology.AsyncCall{Destination: "orders", QueueDelayMillis: 200, NewTrace: newTrace},
+							}},
+							MaxLatencyMillis: 5,
+						},
+					},
+				},
+				"emailservice": {
+					ServiceName: "emailservice",
+					Routes: map[string]*topology.ServiceRoute{
+						"/SendOrderConfirmation": {
+							MaxLatencyMillis: 500,
+							Errors:           []topology.ErrorConfig{{Message: "smtp unavailable"}},
+						},
+					},
+				},
+			},
+		}
+
+		traces := ptrace.NewTraces()
+		g := NewTraceGenerator(topo, rand.New(rand.NewSource(123)), "checkoutservice", "/PlaceOrder")
+		traceId := g.genTraceId()
+		root := g.createSpanForServiceRouteCall(&traces, g.service, g.route, 1000, traceId, pcommon.NewSpanIDEmpty())
+
+		producer := traces.ResourceSpans().At(0).ScopeSpans().At(0).Spans().At(1)
+		consumer := traces.ResourceSpans().At(1).ScopeSpans().At(0).Spans().At(0)
+		require.Equal(t, ptrace.SpanKindProducer, producer.Kind())
+		require.Equal(t, "orders send", producer.Name())
+		require.Equal(t, root.SpanID(), producer.ParentSpanID())
+		require.Equal(t, ptrace.SpanKindConsumer, consumer.Kind())
+
+		// the consumer starts within the queue delay, and the caller neither waits for nor fails with it
+		require.LessOrEqual(t, producer.EndTimestamp(), consumer.StartTimestamp())
+		require.Less(t, consumer.StartTimestamp(), producer.EndTimestamp()+pcommon.Timestamp(200*time.Millisecond))
+		require.LessOrEqual(t, producer.EndTimestamp(), root.EndTimestamp())
+		require.LessOrEqual(t, int64(root.EndTimestamp()), Max(int64(root.StartTimestamp())+int64(5*time.Millisecond), int64(producer.EndTimestamp())))
+		require.Equal(t, ptrace.StatusCodeError, consumer.Status().Code())
+		require.Equal(t, ptrace.StatusCodeUnset, root.Status().Code())
+
+		expected := map[string]string{
+			"messaging.system":           "kafka",
+			"messaging.destination":      "orders",
+			"messaging.destination_kind": "topic",
+		}
+		for k, v := range expected {
+			for _, span := range []ptrace.Span{producer, consumer} {
+				val, ok := span.Attributes().Get(k)
+				require.True(t, ok, k)
+				require.Equal(t, v, val.AsString())
+			}
+		}
+		operation, _ := consumer.Attributes().Get("messaging.operation")
+		require.Equal(t, "process", operation.AsString())
+
+		if newTrace {
+			require.NotEqual(t, traceId, consumer.TraceID())
+			require.True(t, consumer.ParentSpanID().IsEmpty())
+			require.Equal(t, 1, consumer.Links().Len())
+			require.Equal(t, traceId, consumer.Links().At(0).TraceID())
+			require.Equal(t, producer.SpanID(), consumer.Links().At(0).SpanID())
+		} else {
+			require.Equal(t, traceId, consumer.TraceID())
+			require.Equal(t, producer.SpanID(), consumer.ParentSpanID())
+			require.Equal(t, 0, consumer.Links().Len())
+		}
+	}
+}
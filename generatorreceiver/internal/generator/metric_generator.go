@@ -1,12 +1,13 @@
 package generator
 
 import (
+	"math"
 	"math/rand"
-	"time"
 
 	"go.opentelemetry.io/collector/pdata/pcommon"
 	"go.opentelemetry.io/collector/pdata/pmetric"
 
+	"github.com/lightstep/telemetry-generator/generatorreceiver/internal/clock"
 	"github.com/lightstep/telemetry-generator/generatorreceiver/internal/topology"
 )
 
@@ -16,11 +17,9 @@ type MetricGenerator struct {
 }
 
 func NewMetricGenerator(seed int64) *MetricGenerator {
-	r := rand.New(rand.NewSource(seed))
-	r.Seed(seed)
 	return &MetricGenerator{
 		metricCount: 0,
-		random:      r,
+		random:      rand.New(rand.NewSource(seed)),
 	}
 }
 
@@ -37,30 +36,136 @@ func (g *MetricGenerator) Generate(metric *topology.Metric, serviceName string)
 
 	m := rms.ScopeMetrics().AppendEmpty().Metrics().AppendEmpty()
 	m.SetName(metric.Name)
-	if metric.Type == "Gauge" {
+	switch metric.Type {
+	case topology.GaugeType:
 		m.SetEmptyGauge()
 		dp := m.Gauge().DataPoints().AppendEmpty()
-		dp.SetTimestamp(pcommon.NewTimestampFromTime(time.Now()))
+		dp.SetTimestamp(pcommon.NewTimestampFromTime(clock.Now()))
 		dp.SetDoubleValue(metric.GetValue())
-		for k, v := range metric.GetTags() {
-			dp.Attributes().PutStr(k, v)
-		}
-	} else if metric.Type == "Sum" {
-		// TODO: support int-type values
-		// TODO: support cumulative?
+		putTags(dp.Attributes(), metric.GetTags())
+	case topology.SumType:
 		m.SetEmptySum()
-		m.Sum().SetIsMonotonic(true)
-		m.Sum().SetAggregationTemporality(pmetric.AggregationTemporalityDelta)
+		m.Sum().SetIsMonotonic(metric.IsMonotonic())
+		if metric.IsCumulative() {
+			m.Sum().SetAggregationTemporality(pmetric.AggregationTemporalityCumulative)
+		} else {
+			m.Sum().SetAggregationTemporality(pmetric.AggregationTemporalityDelta)
+		}
+		now := clock.Now()
+		start, value := metric.GetSumValue(now)
 		dp := m.Sum().DataPoints().AppendEmpty()
-		dp.SetStartTimestamp(pcommon.NewTimestampFromTime(time.Now()))
-		dp.SetTimestamp(pcommon.NewTimestampFromTime(time.Now()))
-		dp.SetDoubleValue(metric.GetValue())
-		for k, v := range metric.GetTags() {
-			dp.Attributes().PutStr(k, v)
+		dp.SetStartTimestamp(pcommon.NewTimestampFromTime(start))
+		dp.SetTimestamp(pcommon.NewTimestampFromTime(now))
+		if metric.IsInt() {
+			dp.SetIntValue(int64(value))
+		} else {
+			dp.SetDoubleValue(value)
 		}
+		putTags(dp.Attributes(), metric.GetTags())
+	case topology.HistogramType:
+		m.SetEmptyHistogram()
+		m.Histogram().SetAggregationTemporality(pmetric.AggregationTemporalityDelta)
+		now := clock.Now()
+		dp := m.Histogram().DataPoints().AppendEmpty()
+		dp.SetStartTimestamp(pcommon.NewTimestampFromTime(metric.GetHistogramStart(now)))
+		dp.SetTimestamp(pcommon.NewTimestampFromTime(now))
+		fillHistogramDataPoint(dp, metric.GetBuckets(), metric.GetValues())
+		putTags(dp.Attributes(), metric.GetTags())
+	case topology.ExponentialHistogramType:
+		m.SetEmptyExponentialHistogram()
+		m.ExponentialHistogram().SetAggregationTemporality(pmetric.AggregationTemporalityDelta)
+		now := clock.Now()
+		dp := m.ExponentialHistogram().DataPoints().AppendEmpty()
+		dp.SetStartTimestamp(pcommon.NewTimestampFromTime(metric.GetHistogramStart(now)))
+		dp.SetTimestamp(pcommon.NewTimestampFromTime(now))
+		fillExponentialHistogramDataPoint(dp, metric.GetScale(), metric.GetValues())
+		putTags(dp.Attributes(), metric.GetTags())
 	}
-	// TODO: support histograms!
 
 	g.metricCount = g.metricCount + 1
 	return metrics, true
 }
+
+func fillHistogramDataPoint(dp pmetric.HistogramDataPoint, buckets []float64, values []float64) {
+	counts := make([]uint64, len(buckets)+1)
+	for _, v := range values {
+		// a value belongs to the first bucket whose upper boundary is >= v, or to the overflow bucket.
+		i := 0
+		for i < len(buckets) && v > buckets[i] {
+			i++
+		}
+		counts[i]++
+	}
+	dp.ExplicitBounds().FromRaw(buckets)
+	dp.BucketCounts().FromRaw(counts)
+	setHistogramSummary(dp, values)
+}
+
+func fillExponentialHistogramDataPoint(dp pmetric.ExponentialHistogramDataPoint, scale int32, values []float64) {
+	dp.SetScale(scale)
+	positive := map[int32]uint64{}
+	negative := map[int32]uint64{}
+	for _, v := range values {
+		switch {
+		case v > 0:
+			positive[exponentialBucketIndex(v, scale)]++
+		case v < 0:
+			negative[exponentialBucketIndex(-v, scale)]++
+		default:
+			dp.SetZeroCount(dp.ZeroCount() + 1)
+		}
+	}
+	fillExponentialBuckets(dp.Positive(), positive)
+	fillExponentialBuckets(dp.Negative(), negative)
+	setHistogramSummary(dp, values)
+}
+
+// exponentialBucketIndex returns the index of the bucket (base^index, base^(index+1)] holding v,
+// where base = 2^(2^-scale).
+func exponentialBucketIndex(v float64, scale int32) int32 {
+	return int32(math.Ceil(math.Log2(v)*math.Ldexp(1, int(scale)))) - 1
+}
+
+func fillExponentialBuckets(buckets pmetric.ExponentialHistogramDataPointBuckets, counts map[int32]uint64) {
+	if len(counts) == 0 {
+		return
+	}
+	minIndex, maxIndex := int32(math.MaxInt32), int32(math.MinInt32)
+	for i := range counts {
+		if i < minIndex {
+			minIndex = i
+		}
+		if i > maxIndex {
+			maxIndex = i
+		}
+	}
+	raw := make([]uint64, maxIndex-minIndex+1)
+	for i, c := range counts {
+		raw[i-minIndex] = c
+	}
+	buckets.SetOffset(minIndex)
+	buckets.BucketCounts().FromRaw(raw)
+}
+
+type histogramDataPoint interface {
+	SetCount(uint64)
+	SetSum(float64)
+	SetMin(float64)
+	SetMax(float64)
+}
+
+func setHistogramSummary(dp histogramDataPoint, values []float64) {
+	if len(values) == 0 {
+		return
+	}
+	sum, minimum, maximum := 0.0, math.Inf(1), math.Inf(-1)
+	for _, v := range values {
+		sum += v
+		minimum = math.Min(minimum, v)
+		maximum = math.Max(maximum, v)
+	}
+	dp.SetCount(uint64(len(values)))
+	dp.SetSum(sum)
+	dp.SetMin(minimum)
+	dp.SetMax(maximum)
+}
@@ -0,0 +1,118 @@
+package generator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+
+	"github.com/lightstep/telemetry-generator/generatorreceiver/internal/clock"
+	"github.com/lightstep/telemetry-generator/generatorreceiver/internal/flags"
+	"github.com/lightstep/telemetry-generator/generatorreceiver/internal/topology"
+)
+
+func TestMetricGenerator_GenerateHistogram(t *testing.T) {
+	flags.Manager.Clear()
+	metric := &topology.Metric{
+		Name:    "request_latency",
+		Type:    topology.HistogramType,
+		Min:     10,
+		Max:     200,
+		Shape:   topology.Average,
+		Jitter:  0.5,
+		Buckets: []float64{50, 100, 150},
+		Samples: 40,
+		Tags:    map[string]string{"route": "/product"},
+	}
+
+	metrics, report := NewMetricGenerator(123).Generate(metric, "frontend")
+	require.True(t, report)
+
+	m := metrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(0)
+	require.Equal(t, pmetric.MetricTypeHistogram, m.Type())
+	dp := m.Histogram().DataPoints().At(0)
+	require.Equal(t, []float64{50, 100, 150}, dp.ExplicitBounds().AsRaw())
+	require.Equal(t, 4, dp.BucketCounts().Len())
+
+	total := uint64(0)
+	for _, c := range dp.BucketCounts().AsRaw() {
+		total += c
+	}
+	require.Equal(t, uint64(40), total)
+	require.Equal(t, uint64(40), dp.Count())
+	require.GreaterOrEqual(t, dp.Min(), 10.0)
+	require.LessOrEqual(t, dp.Max(), 200.0)
+	require.InDelta(t, dp.Sum()/40, 105, 50)
+	route, _ := dp.Attributes().Get("route")
+	require.Equal(t, "/product", route.AsString())
+}
+
+func TestMetricGenerator_GenerateHistogramSpread(t *testing.T) {
+	flags.Manager.Clear()
+	simulated := clock.NewSimulated(time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC))
+	defer clock.Set(simulated)()
+	metric := &topology.Metric{
+		Name:    "request_latency",
+		Type:    topology.HistogramType,
+		Min:     10,
+		Max:     200,
+		Shape:   topology.Average,
+		Buckets: []float64{50, 100, 150},
+	}
+	generator := NewMetricGenerator(123)
+
+	metrics, _ := generator.Generate(metric, "frontend")
+	first := metrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(0).Histogram().DataPoints().At(0)
+	// without jitter, the samples still spread across every bucket
+	for i, c := range first.BucketCounts().AsRaw() {
+		require.NotZero(t, c, "bucket %d", i)
+	}
+
+	simulated.Advance(15 * time.Second)
+	metrics, _ = generator.Generate(metric, "frontend")
+	second := metrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(0).Histogram().DataPoints().At(0)
+	require.Equal(t, first.Timestamp(), second.StartTimestamp())
+	require.Equal(t, 15*time.Second, second.Timestamp().AsTime().Sub(second.StartTimestamp().AsTime()))
+}
+
+func TestMetricGenerator_GenerateExponentialHistogram(t *testing.T) {
+	flags.Manager.Clear()
+	scale := int32(2)
+	metric := &topology.Metric{
+		Name:   "request_latency",
+		Type:   topology.ExponentialHistogramType,
+		Min:    1,
+		Max:    1000,
+		Shape:  topology.Sine,
+		Jitter: 1,
+		Scale:  &scale,
+	}
+
+	metrics, report := NewMetricGenerator(123).Generate(metric, "frontend")
+	require.True(t, report)
+
+	m := metrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(0)
+	require.Equal(t, pmetric.MetricTypeExponentialHistogram, m.Type())
+	dp := m.ExponentialHistogram().DataPoints().At(0)
+	require.Equal(t, scale, dp.Scale())
+	require.Equal(t, uint64(topology.DefaultHistogramSamples), dp.Count())
+
+	total := dp.ZeroCount()
+	for _, c := range dp.Positive().BucketCounts().AsRaw() {
+		total += c
+	}
+	require.Equal(t, dp.Count(), total)
+	require.Equal(t, 0, dp.Negative().BucketCounts().Len())
+	require.LessOrEqual(t, dp.Positive().Offset(), exponentialBucketIndex(dp.Min(), scale))
+}
+
+func TestExponentialBucketIndex(t *testing.T) {
+	// at scale 0 the buckets are (1, 2], (2, 4], (4, 8]...
+	require.Equal(t, int32(-1), exponentialBucketIndex(1, 0))
+	require.Equal(t, int32(0), exponentialBucketIndex(2, 0))
+	require.Equal(t, int32(1), exponentialBucketIndex(3, 0))
+	require.Equal(t, int32(1), exponentialBucketIndex(4, 0))
+	// at scale 1 the base is sqrt(2), so 3 falls into (2.83, 4]
+	require.Equal(t, int32(3), exponentialBucketIndex(3, 1))
+}
@@ -0,0 +1,55 @@
+package generator
+
+import (
+	"strings"
+
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+
+	"github.com/lightstep/telemetry-generator/generatorreceiver/internal/topology"
+)
+
+// applySemconv puts the attributes of the route's semconv profile on its span. It runs before the
+// route's tags are inserted, so tags can still override any of them.
+func (g *TraceGenerator) applySemconv(span ptrace.Span, s *topology.Semconv, serviceName string, routeName string) {
+	attr := span.Attributes()
+	switch s.Type {
+	case topology.HTTPSemconv:
+		attr.PutStr(string(semconv.HTTPMethodKey), s.GetMethod())
+		attr.PutStr(string(semconv.HTTPSchemeKey), "http")
+		attr.PutStr(string(semconv.HTTPRouteKey), routeName)
+		attr.PutStr(string(semconv.HTTPTargetKey), routeName)
+		code := s.PickStatusCode(span.TraceID())
+		attr.PutInt(string(semconv.HTTPStatusCodeKey), int64(code))
+		if s.IsError(code) {
+			span.Status().SetCode(ptrace.StatusCodeError)
+		}
+	case topology.GRPCSemconv:
+		attr.PutStr(string(semconv.RPCSystemKey), "grpc")
+		attr.PutStr(string(semconv.RPCServiceKey), serviceName)
+		attr.PutStr(string(semconv.RPCMethodKey), strings.TrimPrefix(routeName, "/"))
+		code := s.PickStatusCode(span.TraceID())
+		attr.PutInt(string(semconv.RPCGRPCStatusCodeKey), int64(code))
+		if s.IsError(code) {
+			span.Status().SetCode(ptrace.StatusCodeError)
+		}
+	case topology.DBSemconv:
+		attr.PutStr(string(semconv.DBSystemKey), s.System)
+		if s.DBName != "" {
+			attr.PutStr(string(semconv.DBNameKey), s.DBName)
+		}
+		if statement := s.RenderStatement(g.random, serviceName, routeName, span.TraceID().String(), span.SpanID().String()); strings.TrimSpace(statement) != "" {
+			attr.PutStr(string(semconv.DBStatementKey), statement)
+			attr.PutStr(string(semconv.DBOperationKey), strings.ToUpper(strings.Fields(statement)[0]))
+		}
+	}
+}
+
+// copyStatusCodes copies the HTTP and gRPC status codes of a server span to its client span.
+func copyStatusCodes(from ptrace.Span, to ptrace.Span) {
+	for _, key := range []string{string(semconv.HTTPStatusCodeKey), string(semconv.RPCGRPCStatusCodeKey)} {
+		if val, ok := from.Attributes().Get(key); ok {
+			val.CopyTo(to.Attributes().PutEmpty(key))
+		}
+	}
+}
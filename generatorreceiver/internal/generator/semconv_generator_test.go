@@ -0,0 +1,121 @@
+package generator
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.uber.org/zap"
+
+	"github.com/lightstep/telemetry-generator/generatorreceiver/internal/flags"
+	"github.com/lightstep/telemetry-generator/generatorreceiver/internal/topology"
+)
+
+func TestTraceGenerator_Semconv(t *testing.T) {
+	flags.Manager.Clear()
+	flags.Manager.LoadFlags([]flags.FlagConfig{{Name: "frontend_errors"}}, zap.NewNop())
+	topo := &topology.Topology{
+		Services: map[string]*topology.ServiceTier{
+			"frontend": {
+				ServiceName: "frontend",
+				Routes: map[string]*topology.ServiceRoute{
+					"/product": {
+						DownstreamCalls: []topology.Call{
+							{Service: "productcatalogservice", Route: "/GetProduct", Protocol: topology.GRPCProtocol},
+							{Service: "postgres", Route: "/query"},
+						},
+						MaxLatencyMillis: 10,
+						Semconv: &topology.Semconv{
+							Type: topology.HTTPSemconv,
+							StatusCodes: topology.StatusCodes{
+								{Code: 200},
+								{Code: 503, EmbeddedFlags: flags.EmbeddedFlags{FlagSet: "frontend_errors"}},
+							},
+						},
+					},
+				},
+			},
+			"productcatalogservice": {
+				ServiceName: "productcatalogservice",
+				Routes: map[string]*topology.ServiceRoute{
+					"/GetProduct": {
+						MaxLatencyMillis: 10,
+						Semconv: &topology.Semconv{
+							Type:        topology.GRPCSemconv,
+							StatusCodes: topology.StatusCodes{{Code: 0}},
+						},
+					},
+				},
+			},
+			"postgres": {
+				ServiceName: "postgres",
+				Routes: map[string]*topology.ServiceRoute{
+					"/query": {
+						MaxLatencyMillis: 10,
+						Semconv: &topology.Semconv{
+							Type:       topology.DBSemconv,
+							System:     "postgresql",
+							DBName:     "products",
+							Statements: []string{"select * from products -- $service"},
+						},
+						TagSets: []topology.TagSet{{Tags: topology.TagMap{"db.name": "catalog"}}},
+					},
+				},
+			},
+		},
+	}
+	require.NoError(t, topo.Load())
+
+	generate := func() *ptrace.Traces {
+		return NewTraceGenerator(topo, rand.New(rand.NewSource(123)), "frontend", "/product").Generate(1000)
+	}
+	spansByService := func(traces *ptrace.Traces) map[string]ptrace.Span {
+		spans := map[string]ptrace.Span{}
+		for i := 0; i < traces.ResourceSpans().Len(); i++ {
+			rs := traces.ResourceSpans().At(i)
+			service, _ := rs.Resource().Attributes().Get("service.name")
+			spans[service.AsString()] = rs.ScopeSpans().At(0).Spans().At(0)
+		}
+		return spans
+	}
+	requireAttrs := func(span ptrace.Span, attrs map[string]interface{}) {
+		for k, v := range attrs {
+			val, ok := span.Attributes().Get(k)
+			require.True(t, ok, k)
+			require.Equal(t, v, val.AsRaw(), k)
+		}
+	}
+
+	spans := spansByService(generate())
+	requireAttrs(spans["frontend"], map[string]interface{}{
+		"http.method":      "GET",
+		"http.scheme":      "http",
+		"http.route":       "/product",
+		"http.target":      "/product",
+		"http.status_code": int64(200),
+	})
+	require.Equal(t, ptrace.StatusCodeUnset, spans["frontend"].Status().Code())
+	requireAttrs(spans["productcatalogservice"], map[string]interface{}{
+		"rpc.system":           "grpc",
+		"rpc.service":          "productcatalogservice",
+		"rpc.method":           "GetProduct",
+		"rpc.grpc.status_code": int64(0),
+	})
+	requireAttrs(spans["postgres"], map[string]interface{}{
+		"db.system":    "postgresql",
+		"db.name":      "catalog", // tags override the profile
+		"db.statement": "select * from products -- postgres",
+		"db.operation": "SELECT",
+	})
+
+	// the server's status code is copied to the client span
+	frontendSpans := generate().ResourceSpans().At(0).ScopeSpans().At(0).Spans()
+	requireAttrs(frontendSpans.At(1), map[string]interface{}{"rpc.grpc.status_code": int64(0)})
+
+	// the incident flag flips the route to 5xx
+	flags.Manager.GetFlag("frontend_errors").Enable()
+	spans = spansByService(generate())
+	requireAttrs(spans["frontend"], map[string]interface{}{"http.status_code": int64(503)})
+	require.Equal(t, ptrace.StatusCodeError, spans["frontend"].Status().Code())
+}
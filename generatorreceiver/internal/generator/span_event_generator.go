@@ -0,0 +1,46 @@
+package generator
+
+import (
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+
+	"github.com/lightstep/telemetry-generator/generatorreceiver/internal/topology"
+)
+
+// appendEvents adds the route's events to the span, at their time relative to the span's start and end.
+func (g *TraceGenerator) appendEvents(span ptrace.Span, route *topology.ServiceRoute) {
+	start, end := span.StartTimestamp(), span.EndTimestamp()
+	for i := range route.Events {
+		e := &route.Events[i]
+		if !e.ShouldAdd(g.random) {
+			continue
+		}
+		event := span.Events().AppendEmpty()
+		event.SetName(e.Name)
+		event.SetTimestamp(start + pcommon.Timestamp(e.SampleAt(g.random)*float64(end-start)))
+		attrs := event.Attributes()
+		e.Attributes.InsertTags(&attrs, g.random)
+	}
+}
+
+// appendLinks adds the route's links to the span. Links to the previous trace are skipped for
+// the first trace.
+func (g *TraceGenerator) appendLinks(span ptrace.Span, route *topology.ServiceRoute) {
+	for i := range route.Links {
+		l := &route.Links[i]
+		if !l.ShouldAdd(g.random) {
+			continue
+		}
+		traceId, spanId := g.prevTraceId, g.prevSpanId
+		if !l.PreviousTrace {
+			traceId, spanId = g.genTraceId(), g.genSpanId()
+		} else if spanId.IsEmpty() {
+			continue
+		}
+		link := span.Links().AppendEmpty()
+		link.SetTraceID(traceId)
+		link.SetSpanID(spanId)
+		attrs := link.Attributes()
+		l.Attributes.InsertTags(&attrs, g.random)
+	}
+}
@@ -0,0 +1,73 @@
+package generator
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.uber.org/zap"
+
+	"github.com/lightstep/telemetry-generator/generatorreceiver/internal/flags"
+	"github.com/lightstep/telemetry-generator/generatorreceiver/internal/topology"
+)
+
+func TestTraceGenerator_EventsAndLinks(t *testing.T) {
+	flags.Manager.Clear()
+	flags.Manager.LoadFlags([]flags.FlagConfig{{Name: "cache_miss"}}, zap.NewNop())
+	half, end := 0.5, 1.0
+	topo := &topology.Topology{
+		Services: map[string]*topology.ServiceTier{
+			"frontend": {
+				ServiceName: "frontend",
+				Routes: map[string]*topology.ServiceRoute{
+					"/product": {
+						MaxLatencyMillis: 100,
+						Events: []topology.SpanEvent{
+							{Name: "cache.lookup", At: &half, Attributes: topology.TagMap{"cache.hit": true}},
+							{Name: "response.sent", At: &end},
+							{Name: "cache.miss", EmbeddedFlags: flags.EmbeddedFlags{FlagSet: "cache_miss"}},
+						},
+						Links: []topology.SpanLink{
+							{PreviousTrace: true, Attributes: topology.TagMap{"link.type": "previous"}},
+							{Attributes: topology.TagMap{"link.type": "batch"}},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	g := NewTraceGenerator(topo, rand.New(rand.NewSource(123)), "frontend", "/product")
+	first := g.Generate(1000).ResourceSpans().At(0).ScopeSpans().At(0).Spans().At(0)
+
+	require.Equal(t, 2, first.Events().Len())
+	lookup, sent := first.Events().At(0), first.Events().At(1)
+	require.Equal(t, "cache.lookup", lookup.Name())
+	require.Equal(t, first.StartTimestamp()+(first.EndTimestamp()-first.StartTimestamp())/2, lookup.Timestamp())
+	hit, ok := lookup.Attributes().Get("cache.hit")
+	require.True(t, ok)
+	require.True(t, hit.Bool())
+	require.Equal(t, "response.sent", sent.Name())
+	require.Equal(t, first.EndTimestamp(), sent.Timestamp())
+
+	// the first trace has no previous trace to link to
+	require.Equal(t, 1, first.Links().Len())
+	require.NotEqual(t, first.TraceID(), first.Links().At(0).TraceID())
+
+	flags.Manager.GetFlag("cache_miss").Enable()
+	second := g.Generate(2000).ResourceSpans().At(0).ScopeSpans().At(0).Spans().At(0)
+	require.Equal(t, 3, second.Events().Len())
+	miss := second.Events().At(2)
+	require.Equal(t, "cache.miss", miss.Name())
+	require.GreaterOrEqual(t, miss.Timestamp(), second.StartTimestamp())
+	require.LessOrEqual(t, miss.Timestamp(), second.EndTimestamp())
+
+	require.Equal(t, 2, second.Links().Len())
+	previous := second.Links().At(0)
+	require.Equal(t, first.TraceID(), previous.TraceID())
+	require.Equal(t, first.SpanID(), previous.SpanID())
+	linkType, _ := previous.Attributes().Get("link.type")
+	require.Equal(t, "previous", linkType.AsString())
+	require.NotEqual(t, pcommon.NewTraceIDEmpty(), second.Links().At(1).TraceID())
+}
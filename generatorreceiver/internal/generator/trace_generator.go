@@ -3,10 +3,13 @@ package generator
 import (
 	"fmt"
 	"math/rand"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
 	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
 	"go.opentelemetry.io/collector/pdata/ptrace"
 	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
 
@@ -19,6 +22,10 @@ type TraceGenerator struct {
 	route          string
 	sequenceNumber int
 	random         *rand.Rand
+	logs           *plog.Logs // nil unless logs are requested through GenerateWithLogs
+	// the root span of the previous trace, for links to the previous trace
+	prevTraceId pcommon.TraceID
+	prevSpanId  pcommon.SpanID
 	sync.Mutex
 }
 
@@ -55,19 +62,30 @@ func (g *TraceGenerator) genSpanId() pcommon.SpanID {
 func (g *TraceGenerator) Generate(startTimeNanos int64) *ptrace.Traces {
 	traces := ptrace.NewTraces()
 
-	g.createSpanForServiceRouteCall(&traces, g.service, g.route, startTimeNanos, g.genTraceId(), pcommon.NewSpanIDEmpty())
+	if g.topology.GetServiceTier(g.service).GetRoute(g.route).ShouldGenerate() {
+		root := g.createSpanForServiceRouteCall(&traces, g.service, g.route, startTimeNanos, g.genTraceId(), pcommon.NewSpanIDEmpty())
+		g.prevTraceId, g.prevSpanId = root.TraceID(), root.SpanID()
+	}
 
 	return &traces
 }
 
+// GenerateWithLogs generates a trace along with the log records configured
+// for each of its spans' services and routes.
+func (g *TraceGenerator) GenerateWithLogs(startTimeNanos int64) (*ptrace.Traces, *plog.Logs) {
+	logs := plog.NewLogs()
+	g.logs = &logs
+	defer func() { g.logs = nil }()
+
+	return g.Generate(startTimeNanos), &logs
+}
+
+// createSpanForServiceRouteCall always creates the route's span, callers check that the route is
+// enabled by its flags first.
 func (g *TraceGenerator) createSpanForServiceRouteCall(traces *ptrace.Traces, serviceName string, routeName string, startTimeNanos int64, traceId pcommon.TraceID, parentSpanId pcommon.SpanID) *ptrace.Span {
 	serviceTier := g.topology.GetServiceTier(serviceName)
 	route := serviceTier.GetRoute(routeName)
 
-	if !route.ShouldGenerate() {
-		return nil
-	}
-
 	rspanSlice := traces.ResourceSpans()
 	rspan := rspanSlice.AppendEmpty()
 
@@ -83,51 +101,163 @@ func (g *TraceGenerator) createSpanForServiceRouteCall(traces *ptrace.Traces, se
 	ils := rspan.ScopeSpans().AppendEmpty()
 	spans := ils.Spans()
 
+	if route.Scope != nil {
+		ils.Scope().SetName(route.Scope.Name)
+		ils.Scope().SetVersion(route.Scope.Version)
+	}
+
 	span := spans.AppendEmpty()
 	newSpanId := g.genSpanId()
-	span.SetName(routeName)
+	span.SetName(route.RenderSpanName(serviceTier.ServiceName, routeName, traceId.String(), newSpanId.String()))
 	span.SetTraceID(traceId)
 	span.SetParentSpanID(parentSpanId)
 	span.SetSpanID(newSpanId)
-	span.SetKind(ptrace.SpanKindServer)
+	span.SetKind(route.GetSpanKind())
 	span.Attributes().PutStr("load_generator.seq_num", fmt.Sprintf("%v", g.sequenceNumber))
 
+	if route.Semconv != nil {
+		g.applySemconv(span, route.Semconv, serviceTier.ServiceName, routeName)
+	}
+
 	ts := serviceTier.GetTagSet(routeName, traceId) // ts is single TagSet consisting of tags from the service AND route
 	attr := span.Attributes()
 	ts.Tags.InsertTags(&attr, g.random) // add service and route tags to span attributes
 
 	for _, tg := range ts.TagGenerators {
 		tg.Init(g.random)
-		for k, v := range tg.GetTags() {
-			span.Attributes().PutStr(k, v) // add generated tags to span attributes
-		}
+		putTags(span.Attributes(), tg.GetTags()) // add generated tags to span attributes
+	}
+	if val, ok := span.Attributes().Get("error"); ok && val.AsString() == "true" {
+		span.Status().SetCode(ptrace.StatusCodeError)
 	}
 
-	// TODO: this is still a bit weird - we're calling each downstream route
-	// after a sample of the current route's latency, which doesn't really
-	// make sense - but maybe it's realistic enough?
-	endTime := startTimeNanos + route.SampleLatency(traceId, g.random)
+	// The route's latency is its whole duration, and its downstream calls are made within it from
+	// its start. Parallel calls start together, sequential calls wait for every call before them
+	// to return, and the route finishes its self time after the last call returns, so calls that
+	// don't fit in the latency make the route last longer.
+	latency := route.SampleLatency(traceId, g.random)
+	callsStartTime := startTimeNanos
+	callsEndTime := callsStartTime
 	for _, c := range route.DownstreamCalls {
-		var childStartTimeNanos = startTimeNanos + route.SampleLatency(traceId, g.random)
+		if !g.shouldCall(c) {
+			continue
+		}
+		// repeated calls, e.g. N+1 queries, are timed like the same number of separate calls
+		for n := c.SampleCount(g.random); n > 0; n-- {
+			childStartTimeNanos := callsStartTime
+			if c.IsSequential() {
+				childStartTimeNanos = callsEndTime
+			}
 
-		childSpan := g.createSpanForServiceRouteCall(traces, c.Service, c.Route, childStartTimeNanos, traceId, newSpanId)
-		val, ok := childSpan.Attributes().Get("error")
-		if ok {
-			errorAttr := span.Attributes().PutEmpty("error")
-			val.CopyTo(errorAttr)
+			childEndTime := g.makeCall(traces, spans, span, c, childStartTimeNanos, traceId)
+			if c.IsSequential() {
+				// calls after a sequential call start once it has returned
+				callsStartTime = childEndTime
+			}
+			callsEndTime = Max(callsEndTime, childEndTime)
 		}
-		endTime = Max(endTime, int64(childSpan.EndTimestamp()))
 	}
+	endTime := Max(startTimeNanos+latency, callsEndTime+route.SampleSelfTime(g.random))
 
 	span.SetStartTimestamp(pcommon.NewTimestampFromTime(time.Unix(0, startTimeNanos)))
 	span.SetEndTimestamp(pcommon.NewTimestampFromTime(time.Unix(0, endTime)))
+	g.appendEvents(span, route)
+	g.appendLinks(span, route)
+	if e := route.SampleError(g.random); e != nil {
+		setSpanError(span, e, serviceTier.ServiceName, routeName)
+	}
+	g.appendLogs(resource, serviceTier, route, span)
 	g.sequenceNumber += 1
 	return &span
 }
 
+// shouldCall returns whether a downstream call is made. Calls whose flags or probability skip
+// them, and calls to routes disabled by their flags, leave their edge out of the trace.
+func (g *TraceGenerator) shouldCall(c topology.Call) bool {
+	if !c.ShouldCall(g.random) {
+		return false
+	}
+	if g.topology.GetDependency(c.Service) != nil {
+		return true
+	}
+	return g.topology.GetServiceTier(c.Service).GetRoute(c.Route).ShouldGenerate()
+}
+
+// makeCall creates the spans of a single downstream call made by span, and returns the time the call returns.
+func (g *TraceGenerator) makeCall(traces *ptrace.Traces, spans ptrace.SpanSlice, span ptrace.Span, c topology.Call, startTimeNanos int64, traceId pcommon.TraceID) int64 {
+	if c.IsAsync() {
+		// the caller only waits for the message to be produced, it is unaffected by the consumer
+		return int64(g.createSpansForAsyncCall(traces, spans, c, startTimeNanos, traceId, span.SpanID()).EndTimestamp())
+	}
+
+	callSpan := g.createSpansForCall(traces, spans, c, startTimeNanos, traceId, span.SpanID())
+	// failed calls are retried once the failed attempt has returned
+	for retries := c.GetRetries(); spanFailed(callSpan) && retries > 0; retries-- {
+		callSpan = g.createSpansForCall(traces, spans, c, int64(callSpan.EndTimestamp()), traceId, span.SpanID())
+	}
+	if !c.SwallowsErrors() {
+		propagateError(callSpan, span)
+	}
+	return int64(callSpan.EndTimestamp())
+}
+
+// createSpansForCall creates the CLIENT span of a call in the calling service, parent of the
+// callee's SERVER span unless the callee is a dependency, and returns the CLIENT span.
+func (g *TraceGenerator) createSpansForCall(traces *ptrace.Traces, spans ptrace.SpanSlice, c topology.Call, startTimeNanos int64, traceId pcommon.TraceID, parentSpanId pcommon.SpanID) ptrace.Span {
+	if d := g.topology.GetDependency(c.Service); d != nil {
+		return g.createDependencySpan(spans, d, c, startTimeNanos, traceId, parentSpanId)
+	}
+
+	clientSpan := g.createClientSpan(spans, c, traceId, parentSpanId)
+	serverStartTimeNanos := startTimeNanos + c.SampleNetworkLatency(g.random)
+	childSpan := g.createSpanForServiceRouteCall(traces, c.Service, c.Route, serverStartTimeNanos, traceId, clientSpan.SpanID())
+	propagateError(*childSpan, clientSpan)
+	copyStatusCodes(*childSpan, clientSpan)
+
+	endTimeNanos := int64(childSpan.EndTimestamp()) + c.SampleNetworkLatency(g.random)
+	clientSpan.SetStartTimestamp(pcommon.NewTimestampFromTime(time.Unix(0, startTimeNanos)))
+	clientSpan.SetEndTimestamp(pcommon.NewTimestampFromTime(time.Unix(0, endTimeNanos)))
+	return clientSpan
+}
+
+func (g *TraceGenerator) createClientSpan(spans ptrace.SpanSlice, c topology.Call, traceId pcommon.TraceID, parentSpanId pcommon.SpanID) ptrace.Span {
+	span := spans.AppendEmpty()
+	spanId := g.genSpanId()
+	span.SetName(g.topology.GetServiceTier(c.Service).GetRoute(c.Route).RenderSpanName(c.Service, c.Route, traceId.String(), spanId.String()))
+	span.SetTraceID(traceId)
+	span.SetParentSpanID(parentSpanId)
+	span.SetSpanID(spanId)
+	span.SetKind(ptrace.SpanKindClient)
+
+	attr := span.Attributes()
+	attr.PutStr(string(semconv.PeerServiceKey), c.Service)
+	attr.PutStr(string(semconv.NetPeerNameKey), c.Service)
+	if c.IsGRPC() {
+		attr.PutStr(string(semconv.RPCSystemKey), "grpc")
+		attr.PutStr(string(semconv.RPCServiceKey), c.Service)
+		attr.PutStr(string(semconv.RPCMethodKey), strings.TrimPrefix(c.Route, "/"))
+	} else {
+		attr.PutStr(string(semconv.HTTPURLKey), fmt.Sprintf("http://%s%s", c.Service, c.Route))
+		attr.PutStr(string(semconv.HTTPTargetKey), c.Route)
+	}
+	return span
+}
+
 func Max(x, y int64) int64 {
 	if x < y {
 		return y
 	}
 	return x
 }
+
+// putTags puts tags into attrs in key order, so the generated attributes don't depend on map order.
+func putTags(attrs pcommon.Map, tags map[string]string) {
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		attrs.PutStr(k, tags[k])
+	}
+}
@@ -5,10 +5,12 @@ import (
 	"testing"
 	"time"
 
+	"github.com/lightstep/telemetry-generator/generatorreceiver/internal/flags"
 	"github.com/lightstep/telemetry-generator/generatorreceiver/internal/topology"
 	"github.com/stretchr/testify/require"
 	"go.opentelemetry.io/collector/pdata/pcommon"
 	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.uber.org/zap"
 )
 
 var topologyTestFrontend = topology.ServiceTier{
@@ -120,11 +122,29 @@ func TestTraceGenerator_createSpanForServiceRouteCall2(t *testing.T) {
 			require.Equal(t, rootSpan.ParentSpanID(), pcommon.NewSpanIDEmpty()) //root span will have parent span id of 0
 
 			resourceSpans := traces.ResourceSpans()
+			// the root's resource holds a CLIENT span for each of its downstream calls
+			clientSpans := make(map[pcommon.SpanID]ptrace.Span)
+			rootSpans := resourceSpans.At(0).ScopeSpans().At(0).Spans()
+			require.Equal(t, resourceSpans.Len(), rootSpans.Len())
+			for i := 1; i < rootSpans.Len(); i++ {
+				clientSpan := rootSpans.At(i)
+				require.Equal(t, ptrace.SpanKindClient, clientSpan.Kind())
+				require.Equal(t, rootSpan.SpanID(), clientSpan.ParentSpanID())
+				clientSpans[clientSpan.SpanID()] = clientSpan
+			}
+
 			for i := 1; i < resourceSpans.Len(); i++ {
 
 				resourceSpan := resourceSpans.At(i)
 				scopeSpan := resourceSpan.ScopeSpans().At(0).Spans().At(0)
-				require.Equal(t, scopeSpan.ParentSpanID(), rootSpan.SpanID())
+				require.Equal(t, ptrace.SpanKindServer, scopeSpan.Kind())
+				clientSpan, ok := clientSpans[scopeSpan.ParentSpanID()]
+				require.True(t, ok)
+				require.Equal(t, scopeSpan.Name(), clientSpan.Name())
+				require.LessOrEqual(t, clientSpan.StartTimestamp(), scopeSpan.StartTimestamp())
+				require.GreaterOrEqual(t, clientSpan.EndTimestamp(), scopeSpan.EndTimestamp())
+				peerService, ok := clientSpan.Attributes().Get("peer.service")
+				require.True(t, ok)
 				childSpanStartTime := scopeSpan.StartTimestamp()
 				require.LessOrEqual(t, rootSpan.StartTimestamp(), childSpanStartTime)
 				childSpanEndTime := scopeSpan.EndTimestamp()
@@ -133,6 +153,7 @@ func TestTraceGenerator_createSpanForServiceRouteCall2(t *testing.T) {
 
 				serviceName, ok := resourceSpan.Resource().Attributes().Get("service.name")
 				require.True(t, ok)
+				require.Equal(t, serviceName.AsString(), peerService.AsString())
 				service, ok := testTopology.Services[serviceName.AsString()]
 				require.True(t, ok)
 				_, ok = service.Routes[scopeSpan.Name()]
@@ -150,3 +171,311 @@ func TestTraceGenerator_createSpanForServiceRouteCall2(t *testing.T) {
 		})
 	}
 }
+
+func TestTraceGenerator_DownstreamCallExecution(t *testing.T) {
+	leaf := func(name string) *topology.ServiceTier {
+		return &topology.ServiceTier{
+			ServiceName: name,
+			Routes:      map[string]*topology.ServiceRoute{"/call": {MaxLatencyMillis: 10}},
+		}
+	}
+	topo := &topology.Topology{
+		Services: map[string]*topology.ServiceTier{
+			"frontend": {
+				ServiceName: "frontend",
+				Routes: map[string]*topology.ServiceRoute{
+					"/fanout": {
+						DownstreamCalls: []topology.Call{
+							{Service: "a", Route: "/call"},
+							{Service: "b", Route: "/call", Execution: topology.ParallelExecution},
+							{Service: "c", Route: "/call", Execution: topology.SequentialExecution},
+							{Service: "d", Route: "/call"},
+						},
+						MaxLatencyMillis: 5,
+						SelfTimeMillis:   10,
+					},
+					"/fits": {
+						DownstreamCalls:  []topology.Call{{Service: "a", Route: "/call"}},
+						MaxLatencyMillis: 100,
+					},
+				},
+			},
+			"a": leaf("a"),
+			"b": leaf("b"),
+			"c": leaf("c"),
+			"d": leaf("d"),
+		},
+	}
+
+	for seed := int64(0); seed < 20; seed++ {
+		traces := ptrace.NewTraces()
+		g := NewTraceGenerator(topo, rand.New(rand.NewSource(seed)), "frontend", "/fanout")
+		root := g.createSpanForServiceRouteCall(&traces, g.service, g.route, 1000, g.genTraceId(), pcommon.NewSpanIDEmpty())
+
+		spans := make(map[string]ptrace.Span)
+		resourceSpans := traces.ResourceSpans()
+		for i := 0; i < resourceSpans.Len(); i++ {
+			serviceName, _ := resourceSpans.At(i).Resource().Attributes().Get("service.name")
+			spans[serviceName.AsString()] = resourceSpans.At(i).ScopeSpans().At(0).Spans().At(0)
+		}
+		a, b, c, d := spans["a"], spans["b"], spans["c"], spans["d"]
+
+		// a and b run in parallel from the start of the root, c waits for both, d runs after c
+		require.Equal(t, root.StartTimestamp(), a.StartTimestamp())
+		require.Equal(t, a.StartTimestamp(), b.StartTimestamp())
+		require.Equal(t, Max(int64(a.EndTimestamp()), int64(b.EndTimestamp())), int64(c.StartTimestamp()))
+		require.Equal(t, c.EndTimestamp(), d.StartTimestamp())
+
+		// the root ends within its self time after the last call returns
+		require.GreaterOrEqual(t, root.EndTimestamp(), d.EndTimestamp())
+		require.Less(t, root.EndTimestamp(), d.EndTimestamp()+pcommon.Timestamp(10*time.Millisecond))
+
+		// calls that fit in the latency of the route don't make it last longer
+		g = NewTraceGenerator(topo, rand.New(rand.NewSource(seed)), "frontend", "/fits")
+		root = g.createSpanForServiceRouteCall(&traces, g.service, g.route, 1000, g.genTraceId(), pcommon.NewSpanIDEmpty())
+		require.Less(t, root.EndTimestamp()-root.StartTimestamp(), pcommon.Timestamp(100*time.Millisecond))
+	}
+}
+
+func TestTraceGenerator_ClientSpans(t *testing.T) {
+	topo := &topology.Topology{
+		Services: map[string]*topology.ServiceTier{
+			"frontend": {
+				ServiceName: "frontend",
+				Routes: map[string]*topology.ServiceRoute{
+					"/checkout": {
+						DownstreamCalls: []topology.Call{
+							{Service: "checkoutservice", Route: "/PlaceOrder", Protocol: topology.GRPCProtocol, NetworkLatencyMillis: 5},
+						},
+						MaxLatencyMillis: 5,
+					},
+				},
+			},
+			"checkoutservice": {
+				ServiceName: "checkoutservice",
+				Routes:      map[string]*topology.ServiceRoute{"/PlaceOrder": {MaxLatencyMillis: 10}},
+			},
+		},
+	}
+
+	traces := ptrace.NewTraces()
+	g := NewTraceGenerator(topo, rand.New(rand.NewSource(123)), "frontend", "/checkout")
+	root := g.createSpanForServiceRouteCall(&traces, g.service, g.route, 1000, g.genTraceId(), pcommon.NewSpanIDEmpty())
+
+	client := traces.ResourceSpans().At(0).ScopeSpans().At(0).Spans().At(1)
+	server := traces.ResourceSpans().At(1).ScopeSpans().At(0).Spans().At(0)
+	require.Equal(t, ptrace.SpanKindClient, client.Kind())
+	require.Equal(t, root.SpanID(), client.ParentSpanID())
+	require.Equal(t, client.SpanID(), server.ParentSpanID())
+
+	// the request and the response each spend up to networkLatencyMillis on the network
+	require.LessOrEqual(t, client.StartTimestamp(), server.StartTimestamp())
+	require.Less(t, server.StartTimestamp(), client.StartTimestamp()+pcommon.Timestamp(5*time.Millisecond))
+	require.LessOrEqual(t, server.EndTimestamp(), client.EndTimestamp())
+	require.Less(t, client.EndTimestamp(), server.EndTimestamp()+pcommon.Timestamp(5*time.Millisecond))
+	require.LessOrEqual(t, client.EndTimestamp(), root.EndTimestamp())
+
+	expected := map[string]string{
+		"peer.service":  "checkoutservice",
+		"net.peer.name": "checkoutservice",
+		"rpc.system":    "grpc",
+		"rpc.service":   "checkoutservice",
+		"rpc.method":    "PlaceOrder",
+	}
+	for k, v := range expected {
+		val, ok := client.Attributes().Get(k)
+		require.True(t, ok, k)
+		require.Equal(t, v, val.AsString())
+	}
+}
+
+func TestTraceGenerator_CallFlags(t *testing.T) {
+	flags.Manager.Clear()
+	flags.Manager.LoadFlags([]flags.FlagConfig{{Name: "cache_outage"}, {Name: "maintenance"}}, zap.NewNop())
+	half, never := 0.5, 0.0
+	topo := &topology.Topology{
+		Services: map[string]*topology.ServiceTier{
+			"frontend": {
+				ServiceName: "frontend",
+				Routes: map[string]*topology.ServiceRoute{
+					"/product": {
+						DownstreamCalls: []topology.Call{
+							{Service: "cache", Route: "/get", EmbeddedFlags: flags.EmbeddedFlags{FlagUnset: "cache_outage"}},
+							{Service: "database", Route: "/query", EmbeddedFlags: flags.EmbeddedFlags{FlagSet: "cache_outage"}},
+							{Service: "recommendations", Route: "/get"},
+							{Service: "ads", Route: "/get", Probability: &half},
+							{Service: "tracking", Route: "/get", Probability: &never},
+						},
+						MaxLatencyMillis: 5,
+					},
+				},
+			},
+			"cache":    {ServiceName: "cache", Routes: map[string]*topology.ServiceRoute{"/get": {MaxLatencyMillis: 1}}},
+			"database": {ServiceName: "database", Routes: map[string]*topology.ServiceRoute{"/query": {MaxLatencyMillis: 10}}},
+			"ads":      {ServiceName: "ads", Routes: map[string]*topology.ServiceRoute{"/get": {MaxLatencyMillis: 10}}},
+			"tracking": {ServiceName: "tracking", Routes: map[string]*topology.ServiceRoute{"/get": {MaxLatencyMillis: 10}}},
+			"recommendations": {
+				ServiceName: "recommendations",
+				Routes: map[string]*topology.ServiceRoute{
+					"/get": {MaxLatencyMillis: 10, EmbeddedFlags: flags.EmbeddedFlags{FlagUnset: "maintenance"}},
+				},
+			},
+		},
+	}
+	g := NewTraceGenerator(topo, rand.New(rand.NewSource(123)), "frontend", "/product")
+	calledServices := func() map[string]bool {
+		called := make(map[string]bool)
+		traces := g.Generate(1000)
+		for i := 0; i < traces.ResourceSpans().Len(); i++ {
+			serviceName, _ := traces.ResourceSpans().At(i).Resource().Attributes().Get("service.name")
+			called[serviceName.AsString()] = true
+		}
+		return called
+	}
+
+	called := calledServices()
+	require.True(t, called["cache"])
+	require.False(t, called["database"])
+	require.True(t, called["recommendations"])
+	require.False(t, called["tracking"])
+
+	// flags add and remove edges, and calls to disabled routes are skipped
+	flags.Manager.GetFlag("cache_outage").Enable()
+	flags.Manager.GetFlag("maintenance").Enable()
+	called = calledServices()
+	require.False(t, called["cache"])
+	require.True(t, called["database"])
+	require.False(t, called["recommendations"])
+
+	ads := 0
+	for i := 0; i < 1000; i++ {
+		if calledServices()["ads"] {
+			ads++
+		}
+	}
+	require.InDelta(t, 500, ads, 100)
+}
+
+func TestTraceGenerator_RepeatedCalls(t *testing.T) {
+	flags.Manager.Clear()
+	count := 10
+	topo := &topology.Topology{
+		Services: map[string]*topology.ServiceTier{
+			"cartservice": {
+				ServiceName: "cartservice",
+				Routes: map[string]*topology.ServiceRoute{
+					"/GetCart": {
+						DownstreamCalls: []topology.Call{
+							{Service: "redis", Route: "/get", Count: &count, Execution: topology.SequentialExecution},
+						},
+						MaxLatencyMillis: 5,
+					},
+				},
+			},
+			"redis": {ServiceName: "redis", Routes: map[string]*topology.ServiceRoute{"/get": {MaxLatencyMillis: 2}}},
+		},
+	}
+
+	traces := ptrace.NewTraces()
+	g := NewTraceGenerator(topo, rand.New(rand.NewSource(123)), "cartservice", "/GetCart")
+	root := g.createSpanForServiceRouteCall(&traces, g.service, g.route, 1000, g.genTraceId(), pcommon.NewSpanIDEmpty())
+
+	// all the repeated calls are children of the one parent, made one after another
+	clients := traces.ResourceSpans().At(0).ScopeSpans().At(0).Spans()
+	require.Equal(t, count+1, clients.Len())
+	require.Equal(t, count+1, traces.ResourceSpans().Len())
+	for i := 1; i < clients.Len(); i++ {
+		require.Equal(t, root.SpanID(), clients.At(i).ParentSpanID())
+		if i > 1 {
+			require.Equal(t, clients.At(i-1).EndTimestamp(), clients.At(i).StartTimestamp())
+		}
+	}
+	require.Equal(t, clients.At(count).EndTimestamp(), root.EndTimestamp())
+}
+
+func TestTraceGenerator_SpanNamesKindsAndScopes(t *testing.T) {
+	flags.Manager.Clear()
+	topo := &topology.Topology{
+		Services: map[string]*topology.ServiceTier{
+			"frontend": {
+				ServiceName: "frontend",
+				Routes: map[string]*topology.ServiceRoute{
+					"/product": {
+						DownstreamCalls:  []topology.Call{{Service: "productcatalogservice", Route: "/GetProduct"}},
+						MaxLatencyMillis: 5,
+						SpanName:         "GET /product/{id}",
+						Scope:            &topology.Scope{Name: "go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp", Version: "0.45.0"},
+					},
+				},
+			},
+			"productcatalogservice": {
+				ServiceName: "productcatalogservice",
+				Routes: map[string]*topology.ServiceRoute{
+					"/GetProduct": {MaxLatencyMillis: 5, SpanName: "hipstershop.$service$route", Kind: "Internal"},
+				},
+			},
+		},
+	}
+
+	traces := NewTraceGenerator(topo, rand.New(rand.NewSource(123)), "frontend", "/product").Generate(1000)
+
+	frontend := traces.ResourceSpans().At(0).ScopeSpans().At(0)
+	require.Equal(t, "go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp", frontend.Scope().Name())
+	require.Equal(t, "0.45.0", frontend.Scope().Version())
+	root, client := frontend.Spans().At(0), frontend.Spans().At(1)
+	require.Equal(t, "GET /product/{id}", root.Name())
+	require.Equal(t, ptrace.SpanKindServer, root.Kind())
+	// CLIENT spans are named after the route they call, with its templated variables replaced
+	require.Equal(t, "hipstershop.productcatalogservice/GetProduct", client.Name())
+
+	catalog := traces.ResourceSpans().At(1).ScopeSpans().At(0)
+	require.Equal(t, "", catalog.Scope().Name())
+	require.Equal(t, "hipstershop.productcatalogservice/GetProduct", catalog.Spans().At(0).Name())
+	require.Equal(t, ptrace.SpanKindInternal, catalog.Spans().At(0).Kind())
+}
+
+func TestTraceGenerator_Deterministic(t *testing.T) {
+	flags.Manager.Clear()
+	topo := &topology.Topology{
+		Services: map[string]*topology.ServiceTier{
+			"frontend": {
+				ServiceName: "frontend",
+				Routes: map[string]*topology.ServiceRoute{
+					"/product": {
+						DownstreamCalls: []topology.Call{
+							{Service: "productcatalogservice", Route: "/GetProducts"},
+						},
+						MaxLatencyMillis: 20,
+					},
+				},
+				TagSets: []topology.TagSet{
+					{
+						Tags: topology.TagMap{
+							"region":  []string{"us-east-1", "us-west-2", "eu-west-1"},
+							"zone":    []string{"a", "b", "c"},
+							"user":    []string{"alice", "bob", "carol", "dave"},
+							"version": "v1",
+						},
+						TagGenerators: []topology.TagGenerator{{NumTags: 5, NumVals: 5}},
+					},
+				},
+			},
+			"productcatalogservice": &topologyTestCatalogService,
+		},
+	}
+
+	generate := func(seed int64) [][]byte {
+		g := NewTraceGenerator(topo, rand.New(rand.NewSource(seed)), "frontend", "/product")
+		var out [][]byte
+		for i := int64(0); i < 20; i++ {
+			bytes, err := (&ptrace.ProtoMarshaler{}).MarshalTraces(*g.Generate(i * int64(time.Second)))
+			require.NoError(t, err)
+			out = append(out, bytes)
+		}
+		return out
+	}
+
+	// the same seed generates byte-identical traces, independently of map iteration order
+	require.Equal(t, generate(42), generate(42))
+	require.NotEqual(t, generate(42), generate(43))
+}
@@ -0,0 +1,84 @@
+package topology
+
+import (
+	"fmt"
+	"math/rand"
+
+	"github.com/lightstep/telemetry-generator/generatorreceiver/internal/flags"
+)
+
+// CallCount is a uniform range for the number of times a downstream call is made per span of
+// the calling route, e.g. to model N+1 queries. A CallCount without Max is a fixed count.
+type CallCount struct {
+	Min                 int `json:"min" yaml:"min"`
+	Max                 int `json:"max,omitempty" yaml:"max,omitempty"`
+	flags.EmbeddedFlags `json:",inline" yaml:",inline"`
+}
+
+func (c *CallCount) Sample(random *rand.Rand) int {
+	if c.Max <= c.Min {
+		return c.Min
+	}
+	return c.Min + random.Intn(c.Max-c.Min+1)
+}
+
+func (c *CallCount) validate(fm *flags.FlagManager) error {
+	err := c.ValidateFlags(fm)
+	if err != nil {
+		return err
+	}
+	if c.Min < 0 {
+		return fmt.Errorf("min must not be negative")
+	}
+	if c.Max != 0 && c.Max < c.Min {
+		return fmt.Errorf("max must not be less than min")
+	}
+	return nil
+}
+
+type CallCounts []*CallCount
+
+// Sample samples the first count config enabled by its flags, falling back to the default (no
+// flag_set or flag_unset) config. ok is false when no config applies.
+func (cc CallCounts) Sample(random *rand.Rand) (count int, ok bool) {
+	var defaultCfg *CallCount
+	for _, cfg := range cc {
+		if cfg.IsDefault() {
+			defaultCfg = cfg
+		} else if cfg.ShouldGenerate() {
+			return cfg.Sample(random), true
+		}
+	}
+	if defaultCfg == nil {
+		return 0, false
+	}
+	return defaultCfg.Sample(random), true
+}
+
+func (cc CallCounts) validate(fm *flags.FlagManager) error {
+	hasDefault := false
+	for _, cfg := range cc {
+		err := cfg.validate(fm)
+		if err != nil {
+			return err
+		}
+		if cfg.IsDefault() {
+			if hasDefault {
+				return fmt.Errorf("countConfigs must include at most one default config (no flag_set or flag_unset)")
+			}
+			hasDefault = true
+		}
+	}
+	return nil
+}
+
+// SampleCount samples how many times the call is made: from its countConfigs, else its count, else once.
+func (c *Call) SampleCount(random *rand.Rand) int {
+	if count, ok := c.CountConfigs.Sample(random); ok {
+		return count
+	}
+	if c.Count != nil {
+		return *c.Count
+	}
+	return 1
+}
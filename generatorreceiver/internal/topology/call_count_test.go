@@ -0,0 +1,101 @@
+package topology
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/lightstep/telemetry-generator/generatorreceiver/internal/flags"
+)
+
+func TestCall_SampleCount(t *testing.T) {
+	flags.Manager.Clear()
+	flags.Manager.LoadFlags([]flags.FlagConfig{{Name: "n_plus_one"}}, zap.NewNop())
+	fixed := 3
+	random := rand.New(rand.NewSource(123))
+
+	tests := []struct {
+		name     string
+		call     Call
+		flagOn   bool
+		min, max int
+	}{
+		{name: "default", call: Call{}, min: 1, max: 1},
+		{name: "fixed", call: Call{Count: &fixed}, min: 3, max: 3},
+		{
+			name: "uniform range",
+			call: Call{CountConfigs: CallCounts{{Min: 2, Max: 5}}},
+			min:  2, max: 5,
+		},
+		{
+			name: "flag off uses the default config",
+			call: Call{CountConfigs: CallCounts{
+				{Min: 50, Max: 100, EmbeddedFlags: flags.EmbeddedFlags{FlagSet: "n_plus_one"}},
+				{Min: 1},
+			}},
+			min: 1, max: 1,
+		},
+		{
+			name: "flag on",
+			call: Call{CountConfigs: CallCounts{
+				{Min: 50, Max: 100, EmbeddedFlags: flags.EmbeddedFlags{FlagSet: "n_plus_one"}},
+				{Min: 1},
+			}},
+			flagOn: true,
+			min:    50, max: 100,
+		},
+		{
+			name: "flag off without a default config uses count",
+			call: Call{Count: &fixed, CountConfigs: CallCounts{
+				{Min: 50, EmbeddedFlags: flags.EmbeddedFlags{FlagSet: "n_plus_one"}},
+			}},
+			min: 3, max: 3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flag := flags.Manager.GetFlag("n_plus_one")
+			if tt.flagOn {
+				flag.Enable()
+			} else {
+				flag.Disable()
+			}
+			seen := make(map[int]bool)
+			for i := 0; i < 1000; i++ {
+				count := tt.call.SampleCount(random)
+				require.GreaterOrEqual(t, count, tt.min)
+				require.LessOrEqual(t, count, tt.max)
+				seen[count] = true
+			}
+			require.Len(t, seen, tt.max-tt.min+1)
+		})
+	}
+}
+
+func TestCallCounts_Validate(t *testing.T) {
+	flags.Manager.Clear()
+	tests := []struct {
+		name   string
+		counts CallCounts
+		error  bool
+	}{
+		{name: "fixed and range", counts: CallCounts{{Min: 2, Max: 2}, {Min: 1, Max: 10, EmbeddedFlags: flags.EmbeddedFlags{FlagSet: "n_plus_one"}}}},
+		{name: "negative min", counts: CallCounts{{Min: -1}}, error: true},
+		{name: "max less than min", counts: CallCounts{{Min: 5, Max: 2}}, error: true},
+		{name: "two defaults", counts: CallCounts{{Min: 1}, {Min: 2}}, error: true},
+		{name: "missing flag", counts: CallCounts{{Min: 1, EmbeddedFlags: flags.EmbeddedFlags{FlagSet: "missing"}}}, error: true},
+	}
+	flags.Manager.LoadFlags([]flags.FlagConfig{{Name: "n_plus_one"}}, zap.NewNop())
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.counts.validate(flags.Manager)
+			if tt.error {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
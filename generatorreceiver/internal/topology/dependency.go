@@ -0,0 +1,117 @@
+package topology
+
+import (
+	"fmt"
+	"math/rand"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+
+	"github.com/lightstep/telemetry-generator/generatorreceiver/internal/flags"
+)
+
+const (
+	DatabaseDependency = "database"
+	CacheDependency    = "cache"
+	HTTPDependency     = "http"
+)
+
+// Dependency is a database, cache or external HTTP service that only shows up as the CLIENT spans
+// of the services calling it. Calls name the dependency as their service, and their route is the
+// operation performed, e.g. the table queried or the path requested.
+//
+// Statements and the URL support the same templated variables as log bodies, with the dependency's
+// host as $service and the call's route as $route.
+type Dependency struct {
+	Name             string         `json:"name" yaml:"name"`
+	Type             string         `json:"type" yaml:"type"`                         // database, cache or http
+	System           string         `json:"system,omitempty" yaml:"system,omitempty"` // db.system, e.g. postgresql or redis
+	Host             string         `json:"host,omitempty" yaml:"host,omitempty"`     // net.peer.name, defaults to the dependency's name
+	DBName           string         `json:"dbName,omitempty" yaml:"dbName,omitempty"`
+	Statements       []string       `json:"statements,omitempty" yaml:"statements,omitempty"` // db.statement templates, one is picked per call
+	Method           string         `json:"method,omitempty" yaml:"method,omitempty"`         // http.method, defaults to GET
+	URL              string         `json:"url,omitempty" yaml:"url,omitempty"`               // http.url template, defaults to https://$service$route
+	MaxLatencyMillis int64          `json:"maxLatencyMillis" yaml:"maxLatencyMillis"`
+	LatencyConfigs   LatencyConfigs `json:"latencyConfigs,omitempty" yaml:"latencyConfigs,omitempty"`
+	Errors           []ErrorConfig  `json:"errors,omitempty" yaml:"errors,omitempty"`
+	Attributes       TagMap         `json:"attributes,omitempty" yaml:"attributes,omitempty"`
+}
+
+func (d *Dependency) IsHTTP() bool {
+	return d.Type == HTTPDependency
+}
+
+func (d *Dependency) GetHost() string {
+	if d.Host == "" {
+		return d.Name
+	}
+	return d.Host
+}
+
+func (d *Dependency) GetMethod() string {
+	if d.Method == "" {
+		return "GET"
+	}
+	return d.Method
+}
+
+// RenderStatement picks one of the statement templates and replaces its templated variables.
+func (d *Dependency) RenderStatement(random *rand.Rand, route string, traceID string, spanID string) string {
+	if len(d.Statements) == 0 {
+		return ""
+	}
+	return renderTemplate(d.Statements[random.Intn(len(d.Statements))], d.GetHost(), route, traceID, spanID)
+}
+
+// RenderURL replaces the templated variables in the URL.
+func (d *Dependency) RenderURL(route string, traceID string, spanID string) string {
+	url := d.URL
+	if url == "" {
+		url = "https://" + LogService + LogRoute
+	}
+	return renderTemplate(url, d.GetHost(), route, traceID, spanID)
+}
+
+func (d *Dependency) SampleLatency(traceID pcommon.TraceID, random *rand.Rand) int64 {
+	if d.LatencyConfigs == nil {
+		return random.Int63n(d.MaxLatencyMillis * 1000000)
+	}
+	return d.LatencyConfigs.Sample(traceID, random)
+}
+
+// SampleError returns the error a call to the dependency fails with, or nil if it succeeds.
+func (d *Dependency) SampleError(random *rand.Rand) *ErrorConfig {
+	return sampleError(d.Errors, random)
+}
+
+func (d *Dependency) load(name string) error {
+	d.Name = name
+	if d.LatencyConfigs == nil {
+		return nil
+	}
+	return d.LatencyConfigs.load()
+}
+
+func (d *Dependency) validate(t Topology, fm *flags.FlagManager) error {
+	if t.GetServiceTier(d.Name) != nil {
+		return fmt.Errorf("dependency %s has the same name as a service", d.Name)
+	}
+	switch d.Type {
+	case DatabaseDependency, CacheDependency:
+		if d.System == "" {
+			return fmt.Errorf("dependency %s must have a system defined", d.Name)
+		}
+	case HTTPDependency:
+	default:
+		return fmt.Errorf("dependency %s has invalid type %s, must be %s, %s or %s", d.Name, d.Type, DatabaseDependency, CacheDependency, HTTPDependency)
+	}
+	if d.LatencyConfigs == nil && d.MaxLatencyMillis <= 0 {
+		return fmt.Errorf("dependency %s must have either latencyConfigs or positive, non-zero maxLatencyMillis defined", d.Name)
+	}
+	for i := range d.Errors {
+		err := d.Errors[i].validate(fm)
+		if err != nil {
+			return fmt.Errorf("error with dependency %s errors: %v", d.Name, err)
+		}
+	}
+	return d.LatencyConfigs.validate(fm)
+}
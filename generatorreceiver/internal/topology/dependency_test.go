@@ -0,0 +1,54 @@
+package topology
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lightstep/telemetry-generator/generatorreceiver/internal/flags"
+)
+
+func TestDependency_Validate(t *testing.T) {
+	topo := Topology{
+		Services: map[string]*ServiceTier{
+			"cartservice": {
+				Routes: map[string]*ServiceRoute{
+					"/GetCart": {
+						DownstreamCalls:  []Call{{Service: "redis", Route: "GET"}, {Service: "stripe", Route: "/v1/charges"}},
+						MaxLatencyMillis: 100,
+					},
+					"/AsyncGetCart": {
+						DownstreamCalls:  []Call{{Service: "redis", Route: "GET", Async: &AsyncCall{}}},
+						MaxLatencyMillis: 100,
+					},
+				},
+			},
+		},
+		Dependencies: map[string]*Dependency{
+			"redis":  {Type: CacheDependency, System: "redis", MaxLatencyMillis: 5},
+			"stripe": {Type: HTTPDependency, URL: "https://api.stripe.com$route", MaxLatencyMillis: 300},
+		},
+	}
+	require.NoError(t, topo.Load())
+	require.NoError(t, topo.ValidateDependencies(flags.Manager))
+	require.NoError(t, topo.GetServiceTier("cartservice").GetRoute("/GetCart").validate(topo, flags.Manager))
+	require.Error(t, topo.GetServiceTier("cartservice").GetRoute("/AsyncGetCart").validate(topo, flags.Manager))
+	require.NoError(t, topo.ValidateServiceGraph([]RootRoute{{Service: "cartservice", Route: "/GetCart"}}))
+
+	invalidProbability := 2.0
+	tests := []struct {
+		name       string
+		dependency Dependency
+	}{
+		{name: "same name as a service", dependency: Dependency{Name: "cartservice", Type: HTTPDependency, MaxLatencyMillis: 5}},
+		{name: "invalid type", dependency: Dependency{Name: "s3", Type: "storage", MaxLatencyMillis: 5}},
+		{name: "database without system", dependency: Dependency{Name: "postgres", Type: DatabaseDependency, MaxLatencyMillis: 5}},
+		{name: "missing latency", dependency: Dependency{Name: "postgres", Type: DatabaseDependency, System: "postgresql"}},
+		{name: "invalid error", dependency: Dependency{Name: "s3", Type: HTTPDependency, MaxLatencyMillis: 5, Errors: []ErrorConfig{{Probability: &invalidProbability}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Error(t, tt.dependency.validate(topo, flags.Manager))
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package topology
+
+import (
+	"fmt"
+	"math/rand"
+
+	"github.com/lightstep/telemetry-generator/generatorreceiver/internal/flags"
+)
+
+// ErrorConfig describes how a route's spans fail.
+type ErrorConfig struct {
+	// Probability is the chance of a span failing, see sample.
+	Probability         *float64   `json:"probability,omitempty" yaml:"probability,omitempty"`
+	StatusCode          int        `json:"statusCode,omitempty" yaml:"statusCode,omitempty"` // set as http.status_code on failed spans
+	Message             string     `json:"message,omitempty" yaml:"message,omitempty"`       // span status message
+	Exception           *Exception `json:"exception,omitempty" yaml:"exception,omitempty"`
+	flags.EmbeddedFlags `json:",inline" yaml:",inline"`
+}
+
+// Exception is recorded as an exception event on failed spans. Its message and stacktrace
+// support the same templated variables as log bodies.
+type Exception struct {
+	Type       string `json:"type" yaml:"type"`
+	Message    string `json:"message,omitempty" yaml:"message,omitempty"`
+	Stacktrace string `json:"stacktrace,omitempty" yaml:"stacktrace,omitempty"`
+}
+
+// ShouldFail samples whether a span fails with this error.
+func (e *ErrorConfig) ShouldFail(random *rand.Rand) bool {
+	return e.ShouldGenerate() && sample(e.Probability, random)
+}
+
+// sampleError returns the first of errors that a span fails with, or nil if it succeeds.
+func sampleError(errors []ErrorConfig, random *rand.Rand) *ErrorConfig {
+	for i := range errors {
+		if errors[i].ShouldFail(random) {
+			return &errors[i]
+		}
+	}
+	return nil
+}
+
+// RenderMessage replaces the templated variables in the status message.
+func (e *ErrorConfig) RenderMessage(service string, route string, traceID string, spanID string) string {
+	return renderTemplate(e.Message, service, route, traceID, spanID)
+}
+
+// RenderMessage replaces the templated variables in the exception message.
+func (e *Exception) RenderMessage(service string, route string, traceID string, spanID string) string {
+	return renderTemplate(e.Message, service, route, traceID, spanID)
+}
+
+// RenderStacktrace replaces the templated variables in the exception stacktrace.
+func (e *Exception) RenderStacktrace(service string, route string, traceID string, spanID string) string {
+	return renderTemplate(e.Stacktrace, service, route, traceID, spanID)
+}
+
+func (e *ErrorConfig) validate(fm *flags.FlagManager) error {
+	err := e.ValidateFlags(fm)
+	if err != nil {
+		return err
+	}
+	if !validProbability(e.Probability) {
+		return fmt.Errorf("error probability must be between 0 and 1")
+	}
+	if e.StatusCode != 0 && (e.StatusCode < 100 || e.StatusCode > 599) {
+		return fmt.Errorf("invalid error statusCode %d", e.StatusCode)
+	}
+	if e.Exception != nil && e.Exception.Type == "" {
+		return fmt.Errorf("exception must have a type")
+	}
+	return nil
+}
@@ -21,24 +21,41 @@ type KubernetesConfig struct {
 }
 
 type RootRoute struct {
-	Service             string `json:"service" yaml:"service"`
-	Route               string `json:"route" yaml:"route"`
-	TracesPerHour       int    `json:"tracesPerHour" yaml:"tracesPerHour"`
+	Service             string  `json:"service" yaml:"service"`
+	Route               string  `json:"route" yaml:"route"`
+	TracesPerHour       float64 `json:"tracesPerHour" yaml:"tracesPerHour"`
 	flags.EmbeddedFlags `json:",inline" yaml:",inline"`
+	// RateShape and Multipliers vary the rate around tracesPerHour, and Arrival is uniform (default)
+	// or poisson.
+	RateShape   *RateShape       `json:"rateShape,omitempty" yaml:"rateShape,omitempty"`
+	Multipliers []RateMultiplier `json:"multipliers,omitempty" yaml:"multipliers,omitempty"`
+	Arrival     string           `json:"arrival,omitempty" yaml:"arrival,omitempty"`
 }
 
-func (file *File) ValidateRootRoutes() error {
-	for _, rr := range file.RootRoutes {
+func (file *File) ValidateRootRoutes(fm *flags.FlagManager) error {
+	if problems := file.validateRootRoutes(fm); len(problems) > 0 {
+		return problems[0].Err
+	}
+	return nil
+}
+
+func (file *File) validateRootRoutes(fm *flags.FlagManager) []Problem {
+	var problems []Problem
+	for i, rr := range file.RootRoutes {
 		st := file.Topology.GetServiceTier(rr.Service)
 		if st == nil {
-			return fmt.Errorf("service %s does not exist", rr.Service)
+			problems = append(problems, newProblem(fmt.Errorf("service %s does not exist", rr.Service), "rootRoutes", i, "service"))
+			continue
 		}
 		if st.GetRoute(rr.Route) == nil {
-			return fmt.Errorf("service %s does not have route %s defined", rr.Service, rr.Route)
+			problems = append(problems, newProblem(fmt.Errorf("service %s does not have route %s defined", rr.Service, rr.Route), "rootRoutes", i, "route"))
 		}
 		if rr.TracesPerHour <= 0 {
-			return fmt.Errorf("rootRoute %s must have a positive, non-zero tracesPerHour defined", rr.Route)
+			problems = append(problems, newProblem(fmt.Errorf("rootRoute %s must have a positive, non-zero tracesPerHour defined", rr.Route), "rootRoutes", i, "tracesPerHour"))
+		}
+		if err := rr.validateRate(fm); err != nil {
+			problems = append(problems, newProblem(fmt.Errorf("error with the rate of rootRoute %s: %v", rr.Route, err), "rootRoutes", i))
 		}
 	}
-	return nil
+	return problems
 }
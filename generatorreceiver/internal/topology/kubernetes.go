@@ -1,6 +1,8 @@
 package topology
 
 import (
+	"fmt"
+	"github.com/lightstep/telemetry-generator/generatorreceiver/internal/clock"
 	"github.com/lightstep/telemetry-generator/generatorreceiver/internal/flags"
 	"go.uber.org/zap"
 	"math"
@@ -43,6 +45,8 @@ type Kubernetes struct {
 	Restart     Restart  `json:"restart" yaml:"restart"`
 	PodCount    int      `json:"pod_count" yaml:"pod_count"`
 	Deployment  string   `json:"deployment" yaml:"deployment"`
+	// SumTemporality is the temporality of the generated Sum metrics, delta by default.
+	SumTemporality string `json:"sum_temporality" yaml:"sum_temporality"`
 
 	ReplicaSetName string
 	Service        string
@@ -84,7 +88,7 @@ func (k *Kubernetes) CreatePods(serviceName string, random *rand.Rand) {
 	k.pods = make([]*Pod, k.GetPodCount())
 	for i := 0; i < len(k.pods); i++ {
 		k.pods[i] = &Pod{
-			StartTime:       time.Now(),
+			StartTime:       clock.Now(),
 			PodName:         k.ReplicaSetName + "-" + generateK8sName(5, random),
 			Container:       serviceName,
 			Kubernetes:      k,
@@ -93,6 +97,15 @@ func (k *Kubernetes) CreatePods(serviceName string, random *rand.Rand) {
 	}
 }
 
+func (k *Kubernetes) validate() error {
+	switch k.SumTemporality {
+	case "", DeltaTemporality, CumulativeTemporality:
+		return nil
+	default:
+		return fmt.Errorf("unknown sum_temporality %s", k.SumTemporality)
+	}
+}
+
 func (k *Kubernetes) GetPodCount() int {
 	if k.PodCount > 0 {
 		return k.PodCount
@@ -117,7 +130,7 @@ func (p *Pod) RestartIfNeeded(flags flags.EmbeddedFlags, logger *zap.Logger, ran
 		// TODO: restart with some jitter
 		p.restart(logger, random)
 		return true
-	} else if time.Since(p.StartTime) >= p.RestartDuration {
+	} else if clock.Since(p.StartTime) >= p.RestartDuration {
 		// TODO: restart with some jitter
 		p.restart(logger, random)
 		return true
@@ -126,9 +139,15 @@ func (p *Pod) RestartIfNeeded(flags flags.EmbeddedFlags, logger *zap.Logger, ran
 
 }
 
+func (p *Pod) GetStartTime() time.Time {
+	p.Kubernetes.mutex.Lock()
+	defer p.Kubernetes.mutex.Unlock()
+	return p.StartTime
+}
+
 func (p *Pod) restart(logger *zap.Logger, random *rand.Rand) {
 	// this is locked by RestartIfNeeded
-	p.StartTime = time.Now()
+	p.StartTime = clock.Now()
 	p.RestartDuration = p.Kubernetes.RestartDurationWithJitter(random)
 	p.PodName = p.Kubernetes.ReplicaSetName + "-" + generateK8sName(5, random)
 	logger.Info("pod restarted", zap.String("service", p.Kubernetes.Service), zap.String("pod", p.PodName))
@@ -656,6 +675,9 @@ func (k *Kubernetes) GenerateMetrics() []Metric {
 
 		for i := range podMetrics {
 			podMetrics[i].Pod = pod
+			if podMetrics[i].Type == SumType {
+				podMetrics[i].Temporality = k.SumTemporality
+			}
 			metrics = append(metrics, podMetrics[i])
 		}
 
@@ -1,6 +1,7 @@
 package topology
 
 import (
+	"fmt"
 	"math/rand"
 	"time"
 
@@ -90,6 +91,38 @@ func (l *LatencyPercentiles) loadDurations() error {
 
 type LatencyConfigs []*LatencyPercentiles
 
+func (lcfg LatencyConfigs) load() error {
+	var hasDefault bool
+	var hasWeights bool
+	for _, cfg := range lcfg {
+		if cfg.Weight != 0 {
+			hasWeights = true
+		}
+
+		err := cfg.loadDurations()
+		if err != nil {
+			return fmt.Errorf("error parsing latencyPercentiles: %v", err)
+		}
+		if cfg.IsDefault() {
+			if hasDefault {
+				return fmt.Errorf("latencyConfigs must include exactly one default config (no flag_set or flag_unset)")
+			}
+			hasDefault = true
+		}
+	}
+	if !hasDefault {
+		return fmt.Errorf("latencyConfigs must include exactly one default config (no flag_set or flag_unset)")
+	}
+
+	if !hasWeights {
+		// If there are no weights, everything should have the same weight.
+		for _, config := range lcfg {
+			config.Weight = 1
+		}
+	}
+	return nil
+}
+
 func (lcfg *LatencyConfigs) Sample(traceID pcommon.TraceID, random *rand.Rand) int64 {
 	var defaultCfg *LatencyPercentiles
 	var enabled []*LatencyPercentiles
@@ -109,3 +142,13 @@ func (lcfg *LatencyConfigs) Sample(traceID pcommon.TraceID, random *rand.Rand) i
 	}
 	return defaultCfg.Sample(random)
 }
+
+func (lcfg LatencyConfigs) validate(fm *flags.FlagManager) error {
+	for _, cfg := range lcfg {
+		err := cfg.ValidateFlags(fm)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,331 @@
+package topology
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lightstep/telemetry-generator/generatorreceiver/internal/flags"
+)
+
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// Diagnostic is a problem found by Lint. Path is the path of the YAML node the problem is about,
+// e.g. [topology services frontend routes /product], and Line its line in the file if known.
+type Diagnostic struct {
+	Severity Severity `json:"severity"`
+	Path     []string `json:"path,omitempty"`
+	Line     int      `json:"line,omitempty"`
+	Message  string   `json:"message"`
+}
+
+func (d Diagnostic) String() string {
+	s := fmt.Sprintf("%s: %s", d.Severity, d.Message)
+	if len(d.Path) > 0 {
+		s = fmt.Sprintf("%s: %s: %s", d.Severity, strings.Join(d.Path, "."), d.Message)
+	}
+	if d.Line > 0 {
+		s = fmt.Sprintf("line %d: %s", d.Line, s)
+	}
+	return s
+}
+
+// Problem is an error found while loading or validating part of the topology, along with the path
+// of the YAML node it was found at.
+type Problem struct {
+	Path []string
+	Err  error
+}
+
+func newProblem(err error, path ...interface{}) Problem {
+	p := Problem{Err: err}
+	for _, segment := range path {
+		p.Path = append(p.Path, fmt.Sprint(segment))
+	}
+	return p
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Lint loads and validates the file like the receiver does, but reports every problem rather than
+// the first one. It also warns about things that are valid but most likely mistakes: services and
+// routes that are never called, flags that are never used, weights of zero and latency percentiles
+// that are not increasing. Flags are looked up in fm, which should hold the file's own flags.
+func (file *File) Lint(fm *flags.FlagManager) []Diagnostic {
+	l := &linter{file: file, flags: fm}
+	l.errors()
+	if file.Topology == nil {
+		return l.diagnostics
+	}
+	l.unreachable()
+	l.unusedFlags()
+	walk(reflect.ValueOf(file), nil, l.visit)
+	return l.diagnostics
+}
+
+type linter struct {
+	file        *File
+	flags       *flags.FlagManager
+	diagnostics []Diagnostic
+}
+
+func (l *linter) add(severity Severity, path []string, format string, args ...interface{}) {
+	l.diagnostics = append(l.diagnostics, Diagnostic{
+		Severity: severity,
+		Path:     path,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+func (l *linter) addProblems(prefix []string, problems []Problem) {
+	for _, p := range problems {
+		l.add(SeverityError, append(prefix[:len(prefix):len(prefix)], p.Path...), "%v", p.Err)
+	}
+}
+
+func (l *linter) errors() {
+	for i, f := range l.file.Flags {
+		if err := l.flags.ValidateFlag(f.Name); err != nil {
+			l.add(SeverityError, []string{"flags", strconv.Itoa(i)}, "%v", err)
+		}
+	}
+
+	t := l.file.Topology
+	if t == nil {
+		l.add(SeverityError, []string{"topology"}, "topology file is missing the topology section")
+		return
+	}
+	for _, name := range sortedKeys(t.Dependencies) {
+		path := []string{"topology", "dependencies", name}
+		d := t.Dependencies[name]
+		if d == nil {
+			l.add(SeverityError, path, "dependency %s has no definition", name)
+			continue
+		}
+		if err := d.load(name); err != nil {
+			l.add(SeverityError, path, "error loading dependency %s: %v", name, err)
+		} else if err := d.validate(*t, l.flags); err != nil {
+			l.add(SeverityError, path, "%v", err)
+		}
+	}
+	for _, name := range sortedKeys(t.Services) {
+		path := []string{"topology", "services", name}
+		st := t.Services[name]
+		if st == nil {
+			l.add(SeverityError, path, "service %s has no definition", name)
+			continue
+		}
+		loadProblems := st.loadAll(name)
+		l.addProblems(path, loadProblems)
+		failed := make(map[string]bool)
+		for _, p := range loadProblems {
+			failed[strings.Join(p.Path, ".")] = true
+		}
+		for _, p := range st.validate(*t, l.flags) {
+			// what failed to load is most likely invalid as well, for the same reason
+			if !failed[strings.Join(p.Path, ".")] {
+				l.addProblems(path, []Problem{p})
+			}
+		}
+	}
+	l.addProblems(nil, l.file.validateRootRoutes(l.flags))
+
+	if len(l.diagnostics) == 0 {
+		// the graph can only be traversed once all services and routes are known to exist
+		if err := t.ValidateServiceGraph(l.file.RootRoutes); err != nil {
+			l.add(SeverityError, []string{"rootRoutes"}, "cyclical service graph detected: %v", err)
+		}
+	}
+}
+
+// unreachable warns about the services, dependencies and routes that no root route leads to.
+func (l *linter) unreachable() {
+	t := l.file.Topology
+	reached := make(map[string]map[string]bool)
+	var visit func(service, route string)
+	visit = func(service, route string) {
+		if reached[service][route] {
+			return
+		}
+		if reached[service] == nil {
+			reached[service] = make(map[string]bool)
+		}
+		reached[service][route] = true
+		if st := t.GetServiceTier(service); st != nil {
+			if r := st.GetRoute(route); r != nil {
+				for _, c := range r.DownstreamCalls {
+					visit(c.Service, c.Route)
+				}
+			}
+		}
+	}
+	for _, rr := range l.file.RootRoutes {
+		visit(rr.Service, rr.Route)
+	}
+
+	for _, name := range sortedKeys(t.Services) {
+		path := []string{"topology", "services", name}
+		if reached[name] == nil {
+			l.add(SeverityWarning, path, "service %s is not reachable from any root route", name)
+			continue
+		}
+		if st := t.Services[name]; st != nil {
+			for _, route := range sortedKeys(st.Routes) {
+				if !reached[name][route] {
+					l.add(SeverityWarning, append(path, "routes", route), "route %s of service %s is never called", route, name)
+				}
+			}
+		}
+	}
+	for _, name := range sortedKeys(t.Dependencies) {
+		if reached[name] == nil {
+			l.add(SeverityWarning, []string{"topology", "dependencies", name}, "dependency %s is never called", name)
+		}
+	}
+}
+
+// unusedFlags warns about the flags that are neither used by any flag_set or flag_unset nor are
+// the parent of another flag.
+func (l *linter) unusedFlags() {
+	used := make(map[string]bool)
+	walk(reflect.ValueOf(l.file), nil, func(v reflect.Value, _ []string) {
+		switch f := v.Interface().(type) {
+		case flags.EmbeddedFlags:
+			used[f.FlagSet] = true
+			used[f.FlagUnset] = true
+		case flags.IncidentConfig:
+			used[f.ParentFlag] = true
+		}
+	})
+	for i, f := range l.file.Flags {
+		if !used[f.Name] {
+			l.add(SeverityWarning, []string{"flags", strconv.Itoa(i)}, "flag %s is never used by a flag_set, flag_unset or parentFlag", f.Name)
+		}
+	}
+}
+
+func (l *linter) visit(v reflect.Value, path []string) {
+	if v.Kind() == reflect.Slice {
+		l.lintWeights(v, path)
+	}
+	if v.CanAddr() {
+		if lp, ok := v.Addr().Interface().(*LatencyPercentiles); ok {
+			l.lintLatencyPercentiles(lp, path)
+		}
+	}
+}
+
+// lintWeights warns about the entries of a weighted list that are never picked because of their
+// weight of zero.
+func (l *linter) lintWeights(v reflect.Value, path []string) {
+	elemType := v.Type().Elem()
+	if elemType.Kind() == reflect.Ptr {
+		elemType = elemType.Elem()
+	}
+	if elemType.Kind() != reflect.Struct || !reflect.PtrTo(elemType).Implements(reflect.TypeOf((*Pickable)(nil)).Elem()) {
+		return
+	}
+
+	var zero []int
+	defaults := 0
+	for i := 0; i < v.Len(); i++ {
+		elem := v.Index(i)
+		if elem.Kind() == reflect.Ptr {
+			if elem.IsNil() {
+				continue
+			}
+			elem = elem.Elem()
+		}
+		p := elem.Addr().Interface().(Pickable)
+		if p.GetWeight() == 0 {
+			zero = append(zero, i)
+		}
+		if f, ok := p.(interface{ IsDefault() bool }); ok && f.IsDefault() {
+			defaults++
+		}
+	}
+	switch {
+	case len(zero) == 0:
+	case len(zero) < v.Len():
+		for _, i := range zero {
+			l.add(SeverityWarning, append(path[:len(path):len(path)], strconv.Itoa(i)), "weight is 0 while other entries have weights, so this entry is never picked")
+		}
+	case defaults > 1:
+		l.add(SeverityWarning, path, "none of the %d entries have a weight, so only the last one active is ever picked", v.Len())
+	}
+}
+
+func (l *linter) lintLatencyPercentiles(lp *LatencyPercentiles, path []string) {
+	if lp.loadDurations() != nil {
+		return // reported when loading
+	}
+	d := lp.durations
+	percentiles := []struct {
+		name     string
+		duration time.Duration
+	}{{"p0", d.p0}, {"p50", d.p50}, {"p95", d.p95}, {"p99", d.p99}, {"p99.9", d.p999}, {"p100", d.p100}}
+	for i := 1; i < len(percentiles); i++ {
+		prev, cur := percentiles[i-1], percentiles[i]
+		if cur.duration < prev.duration {
+			l.add(SeverityWarning, append(path[:len(path):len(path)], cur.name), "latency percentile %s (%v) is lower than %s (%v)", cur.name, cur.duration, prev.name, prev.duration)
+		}
+	}
+}
+
+// walk calls visit with v and every value within it, along with their path in the topology file.
+// Struct fields are named after their yaml tag, and fields without one aren't part of the file, so
+// they are skipped.
+func walk(v reflect.Value, path []string, visit func(reflect.Value, []string)) {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+	visit(v, path)
+
+	child := func(segment string) []string {
+		return append(path[:len(path):len(path)], segment)
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			tag := field.Tag.Get("yaml")
+			if field.PkgPath != "" || tag == "" || tag == "-" {
+				continue
+			}
+			name, options, _ := strings.Cut(tag, ",")
+			if strings.Contains(options, "inline") {
+				walk(v.Field(i), path, visit)
+			} else {
+				walk(v.Field(i), child(name), visit)
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			walk(v.Index(i), child(strconv.Itoa(i)), visit)
+		}
+	case reflect.Map:
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return fmt.Sprint(keys[i]) < fmt.Sprint(keys[j]) })
+		for _, k := range keys {
+			walk(v.MapIndex(k), child(fmt.Sprint(k)), visit)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package topology
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/lightstep/telemetry-generator/generatorreceiver/internal/flags"
+)
+
+func TestFile_Lint(t *testing.T) {
+	flags.Manager.Clear()
+	file := &File{
+		Flags: []flags.FlagConfig{
+			{Name: "slow"},
+			{Name: "unused"},
+			{Name: "orphan", Incident: &flags.IncidentConfig{ParentFlag: "missing", Duration: 1}},
+		},
+		Topology: &Topology{
+			Services: map[string]*ServiceTier{
+				"frontend": {
+					Routes: map[string]*ServiceRoute{
+						"/product": {
+							DownstreamCalls: []Call{{Service: "backend", Route: "/GetProduct"}},
+							LatencyConfigs: LatencyConfigs{
+								{P0Cfg: "1ms", P50Cfg: "10ms", P95Cfg: "5ms", P99Cfg: "20ms", P999Cfg: "30ms", P100Cfg: "40ms"},
+								{P0Cfg: "1ms", P50Cfg: "10ms", P95Cfg: "20ms", P99Cfg: "30ms", P999Cfg: "40ms", P100Cfg: "50ms", EmbeddedFlags: flags.EmbeddedFlags{FlagSet: "slow"}},
+							},
+						},
+						"/unused": {MaxLatencyMillis: 10},
+					},
+					TagSets: []TagSet{
+						{EmbeddedWeight: EmbeddedWeight{Weight: 1}, Tags: TagMap{"version": "v1"}},
+						{Tags: TagMap{"version": "v2"}},
+					},
+				},
+				"backend": {
+					Routes: map[string]*ServiceRoute{
+						"/GetProduct": {
+							DownstreamCalls:  []Call{{Service: "missing", Route: "/Get"}},
+							MaxLatencyMillis: 10,
+						},
+					},
+					Metrics: []Metric{{Name: "requests", Type: "Unknown"}},
+				},
+				"orphan": {
+					Routes: map[string]*ServiceRoute{"/Get": {MaxLatencyMillis: 10}},
+				},
+			},
+			Dependencies: map[string]*Dependency{
+				"db": {Type: DatabaseDependency, System: "postgresql", MaxLatencyMillis: 10},
+			},
+		},
+		RootRoutes: []RootRoute{{Service: "frontend", Route: "/product"}},
+	}
+
+	var diagnostics []string
+	for _, d := range file.Lint(flags.NewFlagManager(file.Flags...)) {
+		diagnostics = append(diagnostics, string(d.Severity)+" "+strings.Join(d.Path, "."))
+	}
+	require.Equal(t, []string{
+		"error flags.2",
+		"error topology.services.backend.metrics.0",
+		"error topology.services.backend.routes./GetProduct",
+		"error rootRoutes.0.tracesPerHour",
+		"warning topology.services.frontend.routes./unused",
+		"warning topology.services.orphan",
+		"warning topology.dependencies.db",
+		"warning flags.1",
+		"warning flags.2",
+		"warning topology.services.frontend.routes./product.latencyConfigs.0.p95",
+		"warning topology.services.frontend.tagSets.1",
+	}, diagnostics)
+	require.Zero(t, flags.Manager.FlagCount())
+}
+
+func TestFile_LintWeights(t *testing.T) {
+	flags.Manager.Clear()
+	flags.Manager.LoadFlags([]flags.FlagConfig{{Name: "v3"}}, zap.NewNop())
+	l := &linter{}
+	path := []string{"tagSets"}
+
+	l.lintWeights(reflect.ValueOf([]TagSet{{Tags: TagMap{"version": "v1"}}}), path)
+	require.Empty(t, l.diagnostics, "a single entry is always picked")
+
+	l.lintWeights(reflect.ValueOf([]TagSet{{}, {EmbeddedFlags: flags.EmbeddedFlags{FlagSet: "v3"}}}), path)
+	require.Empty(t, l.diagnostics, "a flagged entry replaces the default one")
+
+	l.lintWeights(reflect.ValueOf([]TagSet{{}, {}}), path)
+	require.Len(t, l.diagnostics, 1)
+	require.Equal(t, path, l.diagnostics[0].Path)
+}
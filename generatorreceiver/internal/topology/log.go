@@ -0,0 +1,101 @@
+package topology
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+
+	"go.opentelemetry.io/collector/pdata/plog"
+
+	"github.com/lightstep/telemetry-generator/generatorreceiver/internal/flags"
+)
+
+const (
+	// Templated variables, these will get replaced in log bodies and error messages.
+
+	LogService = "$service"
+	LogRoute   = "$route"
+	LogTraceID = "$trace_id"
+	LogSpanID  = "$span_id"
+)
+
+var logSeverities = map[string]plog.SeverityNumber{
+	"trace": plog.SeverityNumberTrace,
+	"debug": plog.SeverityNumberDebug,
+	"info":  plog.SeverityNumberInfo,
+	"warn":  plog.SeverityNumberWarn,
+	"error": plog.SeverityNumberError,
+	"fatal": plog.SeverityNumberFatal,
+}
+
+// Log describes log records emitted alongside the spans of a service or route.
+type Log struct {
+	Level  string   `json:"level" yaml:"level"`
+	Bodies []string `json:"bodies" yaml:"bodies"`
+	// Rate is the average number of records emitted per span, fractional rates are sampled.
+	// Defaults to one record per span.
+	Rate                float64 `json:"rate,omitempty" yaml:"rate,omitempty"`
+	Attributes          TagMap  `json:"attributes,omitempty" yaml:"attributes,omitempty"`
+	flags.EmbeddedFlags `json:",inline" yaml:",inline"`
+}
+
+func (l *Log) Severity() plog.SeverityNumber {
+	if l.Level == "" {
+		return plog.SeverityNumberInfo
+	}
+	return logSeverities[strings.ToLower(l.Level)]
+}
+
+// SeverityText returns the upper-cased level, e.g. "INFO".
+func (l *Log) SeverityText() string {
+	if l.Level == "" {
+		return "INFO"
+	}
+	return strings.ToUpper(l.Level)
+}
+
+// SampleCount returns how many records should be emitted for a single span.
+func (l *Log) SampleCount(random *rand.Rand) int {
+	rate := l.Rate
+	if rate == 0 {
+		rate = 1
+	}
+	count := int(rate)
+	if random.Float64() < rate-float64(count) {
+		count++
+	}
+	return count
+}
+
+// RenderBody picks one of the body templates and replaces its templated variables.
+func (l *Log) RenderBody(random *rand.Rand, service string, route string, traceID string, spanID string) string {
+	return renderTemplate(l.Bodies[random.Intn(len(l.Bodies))], service, route, traceID, spanID)
+}
+
+func renderTemplate(template string, service string, route string, traceID string, spanID string) string {
+	return strings.NewReplacer(
+		LogService, service,
+		LogRoute, route,
+		LogTraceID, traceID,
+		LogSpanID, spanID,
+	).Replace(template)
+}
+
+func (l *Log) validate(fm *flags.FlagManager) error {
+	err := l.ValidateFlags(fm)
+	if err != nil {
+		return err
+	}
+	if l.Level != "" {
+		if _, ok := logSeverities[strings.ToLower(l.Level)]; !ok {
+			return fmt.Errorf("unknown log level %s", l.Level)
+		}
+	}
+	if len(l.Bodies) == 0 {
+		return fmt.Errorf("logs must have at least one body defined")
+	}
+	if l.Rate < 0 {
+		return fmt.Errorf("log rate cannot be negative")
+	}
+	return nil
+}
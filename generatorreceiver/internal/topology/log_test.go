@@ -0,0 +1,100 @@
+package topology
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.uber.org/zap"
+
+	"github.com/lightstep/telemetry-generator/generatorreceiver/internal/flags"
+)
+
+func TestLog_Validate(t *testing.T) {
+	tests := []struct {
+		name  string
+		log   Log
+		flags []string
+		error bool
+	}{
+		{
+			name: "Valid log with level and flag",
+			log: Log{
+				Level:         "warn",
+				Bodies:        []string{"slow request on $route"},
+				EmbeddedFlags: flags.EmbeddedFlags{FlagSet: "someFlag"},
+			},
+			flags: []string{"someFlag"},
+			error: false,
+		},
+		{
+			name:  "Unknown level",
+			log:   Log{Level: "loud", Bodies: []string{"hello"}},
+			error: true,
+		},
+		{
+			name:  "Missing bodies",
+			log:   Log{Level: "info"},
+			error: true,
+		},
+		{
+			name:  "Negative rate",
+			log:   Log{Bodies: []string{"hello"}, Rate: -1},
+			error: true,
+		},
+		{
+			name: "Flag was specified but it does not exist",
+			log: Log{
+				Bodies:        []string{"hello"},
+				EmbeddedFlags: flags.EmbeddedFlags{FlagUnset: "fakeFlag"},
+			},
+			error: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flags.Manager.Clear()
+			theFlags := make([]flags.FlagConfig, 0, len(tt.flags))
+			for _, name := range tt.flags {
+				theFlags = append(theFlags, flags.FlagConfig{Name: name})
+			}
+			flags.Manager.LoadFlags(theFlags, zap.NewNop())
+
+			err := tt.log.validate(flags.Manager)
+			if err != nil && !tt.error {
+				assert.Fail(t, fmt.Sprintf("did not expect validation error but got: %v", err))
+			}
+			if err == nil && tt.error {
+				assert.Fail(t, "expected validation error")
+			}
+		})
+	}
+}
+
+func TestLog_Severity(t *testing.T) {
+	require.Equal(t, plog.SeverityNumberInfo, (&Log{}).Severity())
+	require.Equal(t, "INFO", (&Log{}).SeverityText())
+	require.Equal(t, plog.SeverityNumberError, (&Log{Level: "Error"}).Severity())
+	require.Equal(t, "ERROR", (&Log{Level: "Error"}).SeverityText())
+}
+
+func TestLog_SampleCount(t *testing.T) {
+	random := rand.New(rand.NewSource(123))
+	require.Equal(t, 1, (&Log{}).SampleCount(random))
+	require.Equal(t, 3, (&Log{Rate: 3}).SampleCount(random))
+
+	total := 0
+	for i := 0; i < 1000; i++ {
+		total += (&Log{Rate: 0.5}).SampleCount(random)
+	}
+	require.InDelta(t, 500, total, 100)
+}
+
+func TestLog_RenderBody(t *testing.T) {
+	l := &Log{Bodies: []string{"$service handled $route in trace $trace_id span $span_id"}}
+	body := l.RenderBody(rand.New(rand.NewSource(123)), "frontend", "/cart", "abc", "def")
+	require.Equal(t, "frontend handled /cart in trace abc span def", body)
+}
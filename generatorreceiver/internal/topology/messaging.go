@@ -0,0 +1,63 @@
+package topology
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+const (
+	DefaultMessagingSystem = "kafka"
+
+	TopicDestination = "topic"
+	QueueDestination = "queue"
+)
+
+// AsyncCall makes a Call asynchronous: the caller produces a message and the callee consumes it
+// after a queue delay, without the caller waiting for it.
+type AsyncCall struct {
+	System           string `json:"system,omitempty" yaml:"system,omitempty"`                     // defaults to DefaultMessagingSystem
+	Destination      string `json:"destination,omitempty" yaml:"destination,omitempty"`           // defaults to the callee's service name
+	DestinationKind  string `json:"destinationKind,omitempty" yaml:"destinationKind,omitempty"`   // topic (default) or queue
+	QueueDelayMillis int64  `json:"queueDelayMillis,omitempty" yaml:"queueDelayMillis,omitempty"` // maximum time a message waits to be consumed
+	// NewTrace starts the consumer in a new trace, linked to the producer, instead of as the producer's child.
+	NewTrace bool `json:"newTrace,omitempty" yaml:"newTrace,omitempty"`
+}
+
+func (a *AsyncCall) GetSystem() string {
+	if a.System == "" {
+		return DefaultMessagingSystem
+	}
+	return a.System
+}
+
+func (a *AsyncCall) GetDestination(service string) string {
+	if a.Destination == "" {
+		return service
+	}
+	return a.Destination
+}
+
+func (a *AsyncCall) GetDestinationKind() string {
+	if a.DestinationKind == "" {
+		return TopicDestination
+	}
+	return a.DestinationKind
+}
+
+// SampleQueueDelay samples the time a message waits before it is consumed.
+func (a *AsyncCall) SampleQueueDelay(random *rand.Rand) int64 {
+	if a.QueueDelayMillis <= 0 {
+		return 0
+	}
+	return random.Int63n(a.QueueDelayMillis * 1000000)
+}
+
+func (a *AsyncCall) validate() error {
+	if a.DestinationKind != "" && a.DestinationKind != TopicDestination && a.DestinationKind != QueueDestination {
+		return fmt.Errorf("invalid destinationKind %s, must be %s or %s", a.DestinationKind, TopicDestination, QueueDestination)
+	}
+	if a.QueueDelayMillis < 0 {
+		return fmt.Errorf("queueDelayMillis must not be negative")
+	}
+	return nil
+}
@@ -1,6 +1,8 @@
 package topology
 
 import (
+	"fmt"
+	"github.com/lightstep/telemetry-generator/generatorreceiver/internal/clock"
 	"github.com/lightstep/telemetry-generator/generatorreceiver/internal/flags"
 	"math"
 	"math/rand"
@@ -10,6 +12,26 @@ import (
 const DefaultPeriod = 60 * time.Minute
 const DefaultOffset = 0 * time.Minute
 const DefaultMetricTickerPeriod = 15 * time.Second
+const DefaultHistogramSamples = 100
+const DefaultExponentialHistogramScale = 4
+
+// DefaultHistogramBuckets are the explicit bucket boundaries used by the OpenTelemetry SDKs.
+var DefaultHistogramBuckets = []float64{0, 5, 10, 25, 50, 75, 100, 250, 500, 750, 1000, 2500, 5000, 7500, 10000}
+
+const (
+	GaugeType                = "Gauge"
+	SumType                  = "Sum"
+	HistogramType            = "Histogram"
+	ExponentialHistogramType = "ExponentialHistogram"
+)
+
+const (
+	DeltaTemporality      = "delta"
+	CumulativeTemporality = "cumulative"
+
+	DoubleValueType = "double"
+	IntValueType    = "int"
+)
 
 type ShapeInterface interface {
 	GetValue(phase float64) float64
@@ -33,7 +55,7 @@ func (ls *leakingShape) GetValue(phase float64) float64 {
 		return ls.average.GetValue(phase)
 	}
 
-	timeAlive := time.Since(ls.pod.StartTime)
+	timeAlive := clock.Since(ls.pod.StartTime)
 
 	// Start at 35% and increase it to 100% right before the restart time.
 	factor := .35 + float64(timeAlive)/float64(ls.pod.RestartDuration)*.7
@@ -64,9 +86,28 @@ type Metric struct {
 	Tags                map[string]string `json:"tags" yaml:"tags"`
 	TagGenerator        TagGenerator      `json:"tagGenerator,omitempty" yaml:"tagGenerator,omitempty"`
 	Jitter              float64           `json:"jitter" yaml:"jitter"`
+	Buckets             []float64         `json:"buckets,omitempty" yaml:"buckets,omitempty"` // Histogram bucket boundaries
+	Scale               *int32            `json:"scale,omitempty" yaml:"scale,omitempty"`     // ExponentialHistogram bucket resolution
+	Samples             int               `json:"samples,omitempty" yaml:"samples,omitempty"` // values recorded per histogram data point
+	Temporality         string            `json:"temporality,omitempty" yaml:"temporality,omitempty"`
+	ValueType           string            `json:"valueType,omitempty" yaml:"valueType,omitempty"`
+	Monotonic           *bool             `json:"monotonic,omitempty" yaml:"monotonic,omitempty"`
+	Interval            *time.Duration    `json:"interval,omitempty" yaml:"interval,omitempty"` // see ServiceTier.GetMetricInterval
 	flags.EmbeddedFlags `json:",inline" yaml:",inline"`
 	Pod                 *Pod
 	Random              *rand.Rand
+
+	sum sumState
+	// lastHistogram is when the previous histogram data point was reported
+	lastHistogram time.Time
+}
+
+// sumState keeps what a Sum needs between data points to report proper start timestamps and totals.
+type sumState struct {
+	start    time.Time
+	last     time.Time
+	total    float64
+	previous float64
 }
 
 func (m *Metric) GetTags() map[string]string {
@@ -90,26 +131,39 @@ func (m *Metric) InitMetric() {
 		return
 	}
 
-	switch m.Shape {
+	if m.Shape == Leaking {
+		m.ShapeInterface = &leakingShape{average: &funcShape{AverageValue}, pod: m.Pod}
+	} else {
+		m.ShapeInterface = newFuncShape(m.Shape)
+	}
+
+	m.TagGenerator.Init(m.Random)
+}
+
+// newFuncShape returns the shape that only depends on the phase, see shapePhase.
+func newFuncShape(shape Shape) ShapeInterface {
+	switch shape {
 	case Sine:
-		m.ShapeInterface = &funcShape{SineValue}
+		return &funcShape{SineValue}
 	case Sawtooth:
-		m.ShapeInterface = &funcShape{SawtoothValue}
+		return &funcShape{SawtoothValue}
 	case Square:
-		m.ShapeInterface = &funcShape{SquareValue}
+		return &funcShape{SquareValue}
 	case Triangle:
-		m.ShapeInterface = &funcShape{TriangleValue}
+		return &funcShape{TriangleValue}
 	case Average:
-		m.ShapeInterface = &funcShape{AverageValue}
-	case Leaking:
-		m.ShapeInterface = &leakingShape{average: &funcShape{AverageValue}, pod: m.Pod}
+		return &funcShape{AverageValue}
 	default:
 		// TODO: what would be a reasonable default? Maybe just sine?
-		m.ShapeInterface = &funcShape{SineValue}
-
+		return &funcShape{SineValue}
 	}
+}
 
-	m.TagGenerator.Init(m.Random)
+// shapePhase returns how far into its period a shape is at now, from 0 to 1.
+func shapePhase(now time.Time, period, offset time.Duration) float64 {
+	now = now.Add(-offset)
+	since := now.Sub(now.Truncate(period))
+	return float64(since) / float64(period)
 }
 
 func SineValue(phase float64) float64 {
@@ -145,9 +199,7 @@ func (m *Metric) GetValue() float64 {
 		m.Offset = &offset
 	}
 
-	now := time.Now().Add(-*m.Offset)
-	since := now.Sub(now.Truncate(*m.Period))
-	phase := float64(since) / float64(*m.Period)
+	phase := shapePhase(clock.Now(), *m.Period, *m.Offset)
 
 	if m.ShapeInterface == nil {
 		m.InitMetric()
@@ -168,3 +220,158 @@ func (m *Metric) GetValue() float64 {
 
 	return v
 }
+
+// GetValues samples the values recorded into a single histogram data point. They follow a
+// triangular distribution over [m.Min, m.Max] that peaks at the metric's current value, so that
+// they spread across buckets even without jitter while the shape still moves them over time.
+func (m *Metric) GetValues() []float64 {
+	n := m.Samples
+	if n <= 0 {
+		n = DefaultHistogramSamples
+	}
+	peak := m.GetValue()
+	values := make([]float64, n)
+	for i := range values {
+		values[i] = triangular(m.Random.Float64(), m.Min, m.Max, peak)
+	}
+	return values
+}
+
+// triangular maps u in [0, 1) to the triangular distribution over [low, high] with the given peak.
+func triangular(u, low, high, peak float64) float64 {
+	if high <= low {
+		return low
+	}
+	if u < (peak-low)/(high-low) {
+		return low + math.Sqrt(u*(high-low)*(peak-low))
+	}
+	return high - math.Sqrt((1-u)*(high-low)*(high-peak))
+}
+
+// GetHistogramStart returns the start timestamp of the next delta histogram data point reported
+// at now: the previous data point, or the start of the metric's pod if it restarted since.
+func (m *Metric) GetHistogramStart(now time.Time) time.Time {
+	start := m.lastHistogram
+	if m.Pod != nil {
+		if podStart := m.Pod.GetStartTime(); podStart.After(start) {
+			start = podStart
+		}
+	}
+	if start.IsZero() || start.After(now) {
+		start = now
+	}
+	m.lastHistogram = now
+	return start
+}
+
+func (m *Metric) GetBuckets() []float64 {
+	if len(m.Buckets) == 0 {
+		return DefaultHistogramBuckets
+	}
+	return m.Buckets
+}
+
+func (m *Metric) GetScale() int32 {
+	if m.Scale == nil {
+		return DefaultExponentialHistogramScale
+	}
+	return *m.Scale
+}
+
+func (m *Metric) IsCumulative() bool {
+	return m.Temporality == CumulativeTemporality
+}
+
+func (m *Metric) IsInt() bool {
+	return m.ValueType == IntValueType
+}
+
+// IsMonotonic defaults to true, non-monotonic sums behave like UpDownCounters.
+func (m *Metric) IsMonotonic() bool {
+	return m.Monotonic == nil || *m.Monotonic
+}
+
+// GetSumValue returns the start timestamp and value of the next Sum data point reported at now.
+// Monotonic sums treat each sampled value as an increment, which cumulative sums keep adding to
+// their total; non-monotonic sums treat it as the current level, which delta sums report the
+// change of. Cumulative sums start over when the metric's pod restarts.
+func (m *Metric) GetSumValue(now time.Time) (time.Time, float64) {
+	v := m.GetValue()
+	if m.IsInt() {
+		v = math.Round(v)
+	}
+
+	if m.Pod != nil {
+		if podStart := m.Pod.GetStartTime(); podStart.After(m.sum.start) {
+			m.sum = sumState{start: podStart}
+		}
+	}
+	if m.sum.start.IsZero() {
+		m.sum.start = now
+	}
+
+	var start time.Time
+	var value float64
+	if m.IsCumulative() {
+		start = m.sum.start
+		if m.IsMonotonic() {
+			m.sum.total += v
+			value = m.sum.total
+		} else {
+			value = v
+		}
+	} else {
+		start = m.sum.last
+		if start.IsZero() {
+			start = m.sum.start
+		}
+		if m.IsMonotonic() {
+			value = v
+		} else {
+			value = v - m.sum.previous
+		}
+	}
+
+	m.sum.previous = v
+	m.sum.last = now
+	return start, value
+}
+
+func (m *Metric) validate(fm *flags.FlagManager) error {
+	err := m.ValidateFlags(fm)
+	if err != nil {
+		return err
+	}
+	switch m.Type {
+	case GaugeType, SumType:
+	case HistogramType:
+		for i := 1; i < len(m.Buckets); i++ {
+			if m.Buckets[i] <= m.Buckets[i-1] {
+				return fmt.Errorf("histogram buckets must be in strictly increasing order")
+			}
+		}
+	case ExponentialHistogramType:
+		if scale := m.GetScale(); scale < -10 || scale > 20 {
+			return fmt.Errorf("exponential histogram scale must be between -10 and 20")
+		}
+	default:
+		return fmt.Errorf("unknown metric type %s", m.Type)
+	}
+	switch m.Temporality {
+	case "", DeltaTemporality, CumulativeTemporality:
+	default:
+		return fmt.Errorf("unknown temporality %s", m.Temporality)
+	}
+	switch m.ValueType {
+	case "", DoubleValueType, IntValueType:
+	default:
+		return fmt.Errorf("unknown value type %s", m.ValueType)
+	}
+	if m.Samples < 0 {
+		return fmt.Errorf("samples cannot be negative")
+	}
+	if m.Interval != nil && *m.Interval <= 0 {
+		return fmt.Errorf("interval must be positive")
+	}
+	return nil
+}
@@ -2,8 +2,11 @@ package topology
 
 import (
 	"github.com/lightstep/telemetry-generator/generatorreceiver/internal/flags"
+	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
+	"math/rand"
 	"testing"
+	"time"
 )
 
 func TestMetric_ShouldGenerate(t *testing.T) {
@@ -125,3 +128,135 @@ func TestMetric_ShouldGenerate(t *testing.T) {
 		})
 	}
 }
+
+func TestMetric_Validate(t *testing.T) {
+	badScale := int32(21)
+	noInterval := time.Duration(0)
+	tests := []struct {
+		name   string
+		metric Metric
+		error  bool
+	}{
+		{
+			name:   "gauge",
+			metric: Metric{Name: "moot", Type: GaugeType},
+			error:  false,
+		},
+		{
+			name:   "histogram with increasing buckets",
+			metric: Metric{Name: "moot", Type: HistogramType, Buckets: []float64{1, 5, 10}},
+			error:  false,
+		},
+		{
+			name:   "histogram with unordered buckets",
+			metric: Metric{Name: "moot", Type: HistogramType, Buckets: []float64{1, 10, 5}},
+			error:  true,
+		},
+		{
+			name:   "exponential histogram with default scale",
+			metric: Metric{Name: "moot", Type: ExponentialHistogramType},
+			error:  false,
+		},
+		{
+			name:   "exponential histogram with scale out of range",
+			metric: Metric{Name: "moot", Type: ExponentialHistogramType, Scale: &badScale},
+			error:  true,
+		},
+		{
+			name:   "cumulative int sum",
+			metric: Metric{Name: "moot", Type: SumType, Temporality: CumulativeTemporality, ValueType: IntValueType},
+			error:  false,
+		},
+		{
+			name:   "unknown temporality",
+			metric: Metric{Name: "moot", Type: SumType, Temporality: "sometimes"},
+			error:  true,
+		},
+		{
+			name:   "unknown value type",
+			metric: Metric{Name: "moot", Type: SumType, ValueType: "string"},
+			error:  true,
+		},
+		{
+			name:   "unknown type",
+			metric: Metric{Name: "moot", Type: "Summary"},
+			error:  true,
+		},
+		{
+			name:   "interval of zero",
+			metric: Metric{Name: "moot", Type: GaugeType, Interval: &noInterval},
+			error:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.metric.validate(flags.Manager)
+			if err != nil && !tt.error {
+				t.Errorf("did not expect validation error but got: %v", err)
+			}
+			if err == nil && tt.error {
+				t.Errorf("expected validation error")
+			}
+		})
+	}
+}
+
+func TestMetric_GetSumValue(t *testing.T) {
+	notMonotonic := false
+	start := time.Unix(1000, 0)
+	tick := func(i int) time.Time { return start.Add(time.Duration(i) * 15 * time.Second) }
+
+	t.Run("delta monotonic reports each increment since the previous point", func(t *testing.T) {
+		m := Metric{Type: SumType, Min: 2, Max: 2, Random: rand.New(rand.NewSource(1))}
+		s, v := m.GetSumValue(tick(0))
+		require.Equal(t, tick(0), s)
+		require.Equal(t, 2.0, v)
+		s, v = m.GetSumValue(tick(1))
+		require.Equal(t, tick(0), s)
+		require.Equal(t, 2.0, v)
+	})
+
+	t.Run("cumulative monotonic accumulates from a stable start", func(t *testing.T) {
+		m := Metric{Type: SumType, Temporality: CumulativeTemporality, Min: 2.4, Max: 2.4, ValueType: IntValueType, Random: rand.New(rand.NewSource(1))}
+		for i := 0; i < 3; i++ {
+			s, v := m.GetSumValue(tick(i))
+			require.Equal(t, tick(0), s)
+			require.Equal(t, float64(2*(i+1)), v)
+		}
+	})
+
+	t.Run("cumulative resets when the pod restarts", func(t *testing.T) {
+		pod := &Pod{StartTime: start, Kubernetes: &Kubernetes{}}
+		m := Metric{Type: SumType, Temporality: CumulativeTemporality, Min: 1, Max: 1, Pod: pod, Random: rand.New(rand.NewSource(1))}
+		_, _ = m.GetSumValue(tick(1))
+		s, v := m.GetSumValue(tick(2))
+		require.Equal(t, start, s)
+		require.Equal(t, 2.0, v)
+
+		pod.StartTime = tick(3)
+		s, v = m.GetSumValue(tick(4))
+		require.Equal(t, tick(3), s)
+		require.Equal(t, 1.0, v)
+	})
+
+	t.Run("non-monotonic sums report the level or its change", func(t *testing.T) {
+		period := 4 * 15 * time.Second
+		cumulative := Metric{Type: SumType, Temporality: CumulativeTemporality, Monotonic: &notMonotonic, Min: 0, Max: 10, Shape: Average, Period: &period, Random: rand.New(rand.NewSource(1))}
+		delta := Metric{Type: SumType, Monotonic: &notMonotonic, Min: 0, Max: 10, Shape: Average, Period: &period, Random: rand.New(rand.NewSource(1))}
+		for i := 0; i < 2; i++ {
+			_, level := cumulative.GetSumValue(tick(i))
+			require.Equal(t, 5.0, level)
+		}
+		_, change := delta.GetSumValue(tick(0))
+		require.Equal(t, 5.0, change)
+		_, change = delta.GetSumValue(tick(1))
+		require.Equal(t, 0.0, change)
+	})
+}
+
+func TestServiceTier_GetMetricInterval(t *testing.T) {
+	minute, second := time.Minute, time.Second
+	require.Equal(t, DefaultMetricTickerPeriod, (&ServiceTier{}).GetMetricInterval(&Metric{}))
+	require.Equal(t, time.Minute, (&ServiceTier{Interval: &minute}).GetMetricInterval(&Metric{}))
+	require.Equal(t, time.Second, (&ServiceTier{Interval: &minute}).GetMetricInterval(&Metric{Interval: &second}))
+}
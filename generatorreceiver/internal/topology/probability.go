@@ -0,0 +1,14 @@
+package topology
+
+import "math/rand"
+
+// sample returns whether something with probability happens. Probabilities are optional, so that
+// e.g. errors or calls that are gated by flags don't need one: nil means always and 0 never.
+func sample(probability *float64, random *rand.Rand) bool {
+	return probability == nil || random.Float64() < *probability
+}
+
+// validProbability returns whether probability is unset or between 0 and 1.
+func validProbability(probability *float64) bool {
+	return probability == nil || (*probability >= 0 && *probability <= 1)
+}
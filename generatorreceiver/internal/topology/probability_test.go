@@ -0,0 +1,40 @@
+package topology
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSample(t *testing.T) {
+	never, half, always := 0.0, 0.5, 1.0
+	tests := []struct {
+		name        string
+		probability *float64
+		min, max    int
+	}{
+		{name: "unset", probability: nil, min: 1000, max: 1000},
+		{name: "never", probability: &never, min: 0, max: 0},
+		{name: "half", probability: &half, min: 400, max: 600},
+		{name: "always", probability: &always, min: 1000, max: 1000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			random := rand.New(rand.NewSource(123))
+			sampled := 0
+			for i := 0; i < 1000; i++ {
+				if sample(tt.probability, random) {
+					sampled++
+				}
+			}
+			require.GreaterOrEqual(t, sampled, tt.min)
+			require.LessOrEqual(t, sampled, tt.max)
+			require.True(t, validProbability(tt.probability))
+		})
+	}
+
+	negative, overOne := -0.1, 1.1
+	require.False(t, validProbability(&negative))
+	require.False(t, validProbability(&overOne))
+}
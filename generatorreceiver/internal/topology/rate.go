@@ -0,0 +1,111 @@
+package topology
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/lightstep/telemetry-generator/generatorreceiver/internal/flags"
+)
+
+const (
+	UniformArrival = "uniform"
+	PoissonArrival = "poisson"
+)
+
+// RateShape varies the rate of a root route over time, like the shape of a metric varies its
+// value: at each point of the period, tracesPerHour is multiplied by a factor between Min and Max
+// following the shape, e.g. to model daily traffic cycles.
+type RateShape struct {
+	Shape  Shape          `json:"shape" yaml:"shape"`
+	Period *time.Duration `json:"period,omitempty" yaml:"period,omitempty"` // defaults to DefaultPeriod
+	Offset *time.Duration `json:"offset,omitempty" yaml:"offset,omitempty"`
+	Min    float64        `json:"min,omitempty" yaml:"min,omitempty"` // factor at the bottom of the shape, defaults to 0
+	Max    float64        `json:"max,omitempty" yaml:"max,omitempty"` // factor at the top of the shape, defaults to 1
+}
+
+// RateMultiplier multiplies the rate of a root route while its flags are set, e.g. to spike
+// traffic during an incident.
+type RateMultiplier struct {
+	Factor              float64 `json:"factor" yaml:"factor"`
+	flags.EmbeddedFlags `json:",inline" yaml:",inline"`
+}
+
+func (rs *RateShape) GetMax() float64 {
+	if rs.Max == 0 {
+		return 1
+	}
+	return rs.Max
+}
+
+// Factor returns the factor of the rate at now.
+func (rs *RateShape) Factor(now time.Time) float64 {
+	period, offset := DefaultPeriod, DefaultOffset
+	if rs.Period != nil {
+		period = *rs.Period
+	}
+	if rs.Offset != nil {
+		offset = *rs.Offset
+	}
+	value := newFuncShape(rs.Shape).GetValue(shapePhase(now, period, offset))
+	return rs.Min + (rs.GetMax()-rs.Min)*value
+}
+
+func (rs *RateShape) validate() error {
+	switch rs.Shape {
+	case Sine, Sawtooth, Square, Triangle, Average:
+	default:
+		return fmt.Errorf("unknown rate shape %s, must be %s, %s, %s, %s or %s", rs.Shape, Sine, Sawtooth, Square, Triangle, Average)
+	}
+	if rs.Period != nil && *rs.Period <= 0 {
+		return fmt.Errorf("rate shape period must be positive")
+	}
+	if rs.Min < 0 || rs.GetMax() < rs.Min {
+		return fmt.Errorf("rate shape min must be positive and no higher than max")
+	}
+	return nil
+}
+
+// RateFactor returns what tracesPerHour is multiplied by at now: the factor of the rate shape, if
+// any, times the factors of the multipliers whose flags are set.
+func (rr *RootRoute) RateFactor(now time.Time) float64 {
+	factor := 1.0
+	if rr.RateShape != nil {
+		factor = rr.RateShape.Factor(now)
+	}
+	for _, m := range rr.Multipliers {
+		if m.ShouldGenerate() {
+			factor *= m.Factor
+		}
+	}
+	return factor
+}
+
+// IsPoisson reports whether traces arrive at random, exponentially distributed intervals rather
+// than at uniform intervals.
+func (rr *RootRoute) IsPoisson() bool {
+	return rr.Arrival == PoissonArrival
+}
+
+func (rr *RootRoute) validateRate(fm *flags.FlagManager) error {
+	if rr.RateShape != nil {
+		err := rr.RateShape.validate()
+		if err != nil {
+			return err
+		}
+	}
+	for _, m := range rr.Multipliers {
+		if m.Factor < 0 {
+			return fmt.Errorf("rate multiplier factor cannot be negative")
+		}
+		err := m.ValidateFlags(fm)
+		if err != nil {
+			return err
+		}
+	}
+	switch rr.Arrival {
+	case "", UniformArrival, PoissonArrival:
+	default:
+		return fmt.Errorf("unknown arrival %s, must be %s or %s", rr.Arrival, UniformArrival, PoissonArrival)
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package topology
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/lightstep/telemetry-generator/generatorreceiver/internal/flags"
+)
+
+func TestRootRoute_RateFactor(t *testing.T) {
+	flags.Manager.Clear()
+	flags.Manager.LoadFlags([]flags.FlagConfig{{Name: "spike"}}, zap.NewNop())
+	period := 24 * time.Hour
+	rr := RootRoute{
+		TracesPerHour: 3600,
+		RateShape:     &RateShape{Shape: Triangle, Period: &period, Min: 0.5, Max: 2},
+		Multipliers:   []RateMultiplier{{Factor: 10, EmbeddedFlags: flags.EmbeddedFlags{FlagSet: "spike"}}},
+	}
+	midnight := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	require.InDelta(t, 0.5, rr.RateFactor(midnight), 1e-9)
+	require.InDelta(t, 2, rr.RateFactor(midnight.Add(12*time.Hour)), 1e-9)
+	require.InDelta(t, 1.25, rr.RateFactor(midnight.Add(6*time.Hour)), 1e-9)
+
+	flags.Manager.GetFlag("spike").Enable()
+	require.InDelta(t, 20, rr.RateFactor(midnight.Add(12*time.Hour)), 1e-9)
+
+	require.Equal(t, 1.0, (&RootRoute{}).RateFactor(midnight))
+	require.Equal(t, 1.0, (&RateShape{Shape: Square}).Factor(midnight.Add(45*time.Minute)), "max defaults to 1")
+}
+
+func TestRootRoute_ValidateRate(t *testing.T) {
+	flags.Manager.Clear()
+	negative := -time.Hour
+	tests := []struct {
+		name  string
+		rr    RootRoute
+		error bool
+	}{
+		{name: "no rate shaping", rr: RootRoute{}},
+		{name: "poisson arrival", rr: RootRoute{Arrival: PoissonArrival, RateShape: &RateShape{Shape: Sine}}},
+		{name: "unknown arrival", rr: RootRoute{Arrival: "bursty"}, error: true},
+		{name: "unknown shape", rr: RootRoute{RateShape: &RateShape{Shape: Leaking}}, error: true},
+		{name: "negative period", rr: RootRoute{RateShape: &RateShape{Shape: Sine, Period: &negative}}, error: true},
+		{name: "min above max", rr: RootRoute{RateShape: &RateShape{Shape: Sine, Min: 2, Max: 1}}, error: true},
+		{name: "negative multiplier", rr: RootRoute{Multipliers: []RateMultiplier{{Factor: -1}}}, error: true},
+		{name: "missing multiplier flag", rr: RootRoute{Multipliers: []RateMultiplier{{Factor: 2, EmbeddedFlags: flags.EmbeddedFlags{FlagSet: "missing"}}}}, error: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.rr.validateRate(flags.Manager)
+			if tt.error {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package topology
+
+import (
+	"fmt"
+	"math/rand"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+
+	"github.com/lightstep/telemetry-generator/generatorreceiver/internal/flags"
+)
+
+const (
+	HTTPSemconv = "http"
+	GRPCSemconv = "grpc"
+	DBSemconv   = "db"
+)
+
+// Semconv fills in the semantic convention attributes of an HTTP server, gRPC server or database
+// route, so they don't have to be written by hand in tagSets.
+type Semconv struct {
+	Type        string      `json:"type" yaml:"type"`                                   // http, grpc or db
+	Method      string      `json:"method,omitempty" yaml:"method,omitempty"`           // http.method, defaults to GET
+	System      string      `json:"system,omitempty" yaml:"system,omitempty"`           // db.system
+	DBName      string      `json:"dbName,omitempty" yaml:"dbName,omitempty"`           // db.name
+	Statements  []string    `json:"statements,omitempty" yaml:"statements,omitempty"`   // db.statement, one is picked per span
+	StatusCodes StatusCodes `json:"statusCodes,omitempty" yaml:"statusCodes,omitempty"` // http.status_code or rpc.grpc.status_code
+}
+
+// StatusCode is a weighted HTTP or gRPC status code. Like latencyConfigs, status codes with
+// flag_set or flag_unset replace the default ones while they are enabled, e.g. to flip a route to
+// 5xx during an incident.
+type StatusCode struct {
+	Code                int `json:"code" yaml:"code"`
+	EmbeddedWeight      `json:",inline" yaml:",inline"`
+	flags.EmbeddedFlags `json:",inline" yaml:",inline"`
+}
+
+type StatusCodes []*StatusCode
+
+// Pick picks a status code, from the ones enabled by flags if there are any. ok is false when
+// there are no status codes to pick from.
+func (sc StatusCodes) Pick(traceID pcommon.TraceID) (code int, ok bool) {
+	var defaults, enabled []*StatusCode
+	for _, c := range sc {
+		if c.IsDefault() {
+			defaults = append(defaults, c)
+		} else if c.ShouldGenerate() {
+			enabled = append(enabled, c)
+		}
+	}
+	if len(enabled) == 0 {
+		enabled = defaults
+	}
+	if picked := pickBasedOnWeight(enabled, traceID); picked != nil {
+		return picked.Code, true
+	}
+	return 0, false
+}
+
+// PickStatusCode picks the span's status code, defaulting to 200 for HTTP and OK for gRPC.
+func (s *Semconv) PickStatusCode(traceID pcommon.TraceID) int {
+	if code, ok := s.StatusCodes.Pick(traceID); ok {
+		return code
+	}
+	if s.Type == HTTPSemconv {
+		return 200
+	}
+	return 0
+}
+
+// IsError returns whether the status code fails the span: 5xx for HTTP servers, anything but OK for gRPC.
+func (s *Semconv) IsError(code int) bool {
+	if s.Type == HTTPSemconv {
+		return code >= 500
+	}
+	return code != 0
+}
+
+func (s *Semconv) GetMethod() string {
+	if s.Method == "" {
+		return "GET"
+	}
+	return s.Method
+}
+
+// RenderStatement picks one of the statement templates and replaces its templated variables.
+func (s *Semconv) RenderStatement(random *rand.Rand, service string, route string, traceID string, spanID string) string {
+	if len(s.Statements) == 0 {
+		return ""
+	}
+	return renderTemplate(s.Statements[random.Intn(len(s.Statements))], service, route, traceID, spanID)
+}
+
+func (s *Semconv) load() {
+	hasWeights := false
+	for _, c := range s.StatusCodes {
+		if c.Weight != 0 {
+			hasWeights = true
+		}
+	}
+	if !hasWeights {
+		// If there are no weights, everything should have the same weight.
+		for _, c := range s.StatusCodes {
+			c.Weight = 1
+		}
+	}
+}
+
+func (s *Semconv) validate(fm *flags.FlagManager) error {
+	minCode, maxCode := 0, 0
+	switch s.Type {
+	case HTTPSemconv:
+		minCode, maxCode = 100, 599
+	case GRPCSemconv:
+		minCode, maxCode = 0, 16
+	case DBSemconv:
+		if s.System == "" {
+			return fmt.Errorf("db semconv must have a system defined")
+		}
+		if len(s.StatusCodes) > 0 {
+			return fmt.Errorf("db semconv cannot have statusCodes")
+		}
+	default:
+		return fmt.Errorf("invalid semconv type %s, must be %s, %s or %s", s.Type, HTTPSemconv, GRPCSemconv, DBSemconv)
+	}
+	for _, c := range s.StatusCodes {
+		err := c.ValidateFlags(fm)
+		if err != nil {
+			return err
+		}
+		if c.Code < minCode || c.Code > maxCode {
+			return fmt.Errorf("invalid %s status code %d", s.Type, c.Code)
+		}
+	}
+	return nil
+}
@@ -3,52 +3,249 @@ package topology
 import (
 	"fmt"
 	"math/rand"
+	"strings"
 
 	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
 
 	"github.com/lightstep/telemetry-generator/generatorreceiver/internal/flags"
 )
 
+const (
+	ParallelExecution   = "parallel"
+	SequentialExecution = "sequential"
+
+	HTTPProtocol = "http"
+	GRPCProtocol = "grpc"
+
+	PropagateErrors = "propagate"
+	RetryErrors     = "retry"
+	SwallowErrors   = "swallow"
+
+	DefaultRetries = 1
+)
+
 type ServiceRoute struct {
 	Route               string         `json:"route" yaml:"route"`
 	DownstreamCalls     []Call         `json:"downstreamCalls,omitempty" yaml:"downstreamCalls,omitempty"`
 	MaxLatencyMillis    int64          `json:"maxLatencyMillis" yaml:"maxLatencyMillis"`
+	SelfTimeMillis      int64          `json:"selfTimeMillis,omitempty" yaml:"selfTimeMillis,omitempty"` // processing time after the last downstream call returns
 	LatencyConfigs      LatencyConfigs `json:"latencyConfigs" yaml:"latencyConfigs"`
 	TagSets             []TagSet       `json:"tagSets" yaml:"tagSets"`
+	Logs                []Log          `json:"logs,omitempty" yaml:"logs,omitempty"`
+	Errors              []ErrorConfig  `json:"errors,omitempty" yaml:"errors,omitempty"`
+	Events              []SpanEvent    `json:"events,omitempty" yaml:"events,omitempty"`
+	Links               []SpanLink     `json:"links,omitempty" yaml:"links,omitempty"`
+	SpanName            string         `json:"spanName,omitempty" yaml:"spanName,omitempty"` // defaults to the route, see RenderSpanName
+	Kind                string         `json:"kind,omitempty" yaml:"kind,omitempty"`         // server (default), internal, client, producer or consumer
+	Scope               *Scope         `json:"scope,omitempty" yaml:"scope,omitempty"`
+	Semconv             *Semconv       `json:"semconv,omitempty" yaml:"semconv,omitempty"`
 	flags.EmbeddedFlags `json:",inline" yaml:",inline"`
 	// TODO: rename all references from `tag` to `attribute`, to follow the otel standard.
 }
 
+// Scope is the instrumentation scope that a route's spans are reported with.
+type Scope struct {
+	Name    string `json:"name" yaml:"name"`
+	Version string `json:"version,omitempty" yaml:"version,omitempty"`
+}
+
+var spanKinds = map[string]ptrace.SpanKind{
+	"server":   ptrace.SpanKindServer,
+	"internal": ptrace.SpanKindInternal,
+	"client":   ptrace.SpanKindClient,
+	"producer": ptrace.SpanKindProducer,
+	"consumer": ptrace.SpanKindConsumer,
+}
+
 type Call struct {
-	Service string `json:"service" yaml:"service"`
-	Route   string `json:"route" yaml:"route"`
-	//TODO: flags.EmbeddedFlags   `json:",inline" yaml:",inline"`
+	Service   string `json:"service" yaml:"service"`
+	Route     string `json:"route" yaml:"route"`
+	Execution string `json:"execution,omitempty" yaml:"execution,omitempty"` // parallel (default) or sequential
+	Protocol  string `json:"protocol,omitempty" yaml:"protocol,omitempty"`   // http (default) or grpc
+	// NetworkLatencyMillis is the maximum time the request, and separately the response, spends on the network.
+	NetworkLatencyMillis int64 `json:"networkLatencyMillis,omitempty" yaml:"networkLatencyMillis,omitempty"`
+	// OnError is what the caller does when the call fails: propagate (default) the error, retry the call, or swallow the error.
+	OnError string `json:"onError,omitempty" yaml:"onError,omitempty"`
+	Retries int    `json:"retries,omitempty" yaml:"retries,omitempty"` // attempts after the first when retrying, defaults to DefaultRetries
+	// Async makes the call through a message queue: the caller produces a message that the callee consumes.
+	Async *AsyncCall `json:"async,omitempty" yaml:"async,omitempty"`
+	// Count is the number of times the call is made per span, defaults to 1. CountConfigs sample the
+	// count instead, e.g. to model N+1 queries.
+	Count        *int       `json:"count,omitempty" yaml:"count,omitempty"`
+	CountConfigs CallCounts `json:"countConfigs,omitempty" yaml:"countConfigs,omitempty"`
+	// Probability is the chance of the call being made, see sample.
+	Probability         *float64 `json:"probability,omitempty" yaml:"probability,omitempty"`
+	flags.EmbeddedFlags `json:",inline" yaml:",inline"`
 }
 
-func (r *ServiceRoute) validate(t Topology) error {
-	err := r.ValidateFlags()
+func (r *ServiceRoute) validate(t Topology, fm *flags.FlagManager) error {
+	err := r.ValidateFlags(fm)
 	if err != nil {
 		return err
 	}
 
 	for _, call := range r.DownstreamCalls {
-		st := t.GetServiceTier(call.Service)
-		if st == nil {
+		if t.GetDependency(call.Service) != nil {
+			if call.IsAsync() {
+				return fmt.Errorf("downstream call to dependency %s cannot be async", call.Service)
+			}
+		} else if st := t.GetServiceTier(call.Service); st == nil {
 			return fmt.Errorf("downstream service %s does not exist", call.Service)
-		}
-		if st.GetRoute(call.Route) == nil {
+		} else if st.GetRoute(call.Route) == nil {
 			return fmt.Errorf("downstream service %s does not have route %s defined", call.Service, call.Route)
 		}
+		err = call.validate(fm)
+		if err != nil {
+			return err
+		}
+	}
+
+	if _, ok := spanKinds[strings.ToLower(r.Kind)]; r.Kind != "" && !ok {
+		return fmt.Errorf("invalid span kind %s", r.Kind)
+	}
+	if r.Scope != nil && r.Scope.Name == "" {
+		return fmt.Errorf("scope must have a name")
+	}
+	if r.Semconv != nil {
+		err = r.Semconv.validate(fm)
+		if err != nil {
+			return fmt.Errorf("error with semconv: %v", err)
+		}
+	}
+
+	if r.SelfTimeMillis < 0 {
+		return fmt.Errorf("selfTimeMillis must not be negative")
+	}
+
+	for i := range r.Logs {
+		err = r.Logs[i].validate(fm)
+		if err != nil {
+			return fmt.Errorf("error with logs: %v", err)
+		}
+	}
+
+	for i := range r.Errors {
+		err = r.Errors[i].validate(fm)
+		if err != nil {
+			return fmt.Errorf("error with error config: %v", err)
+		}
+	}
+
+	for i := range r.Events {
+		err = r.Events[i].validate(fm)
+		if err != nil {
+			return fmt.Errorf("error with events: %v", err)
+		}
+	}
+
+	for i := range r.Links {
+		err = r.Links[i].validate(fm)
+		if err != nil {
+			return fmt.Errorf("error with links: %v", err)
+		}
 	}
 
 	if r.LatencyConfigs == nil && r.MaxLatencyMillis <= 0 {
 		return fmt.Errorf("must have either latencyPercentiles or positive, non-zero maxLatencyMillis defined")
 	}
+	return r.LatencyConfigs.validate(fm)
+}
+
+func (c *Call) validate(fm *flags.FlagManager) error {
+	err := c.ValidateFlags(fm)
+	if err != nil {
+		return fmt.Errorf("downstream call to %s %s: %v", c.Service, c.Route, err)
+	}
+	if !validProbability(c.Probability) {
+		return fmt.Errorf("downstream call to %s %s has invalid probability, must be between 0 and 1", c.Service, c.Route)
+	}
+	if c.Count != nil && *c.Count < 0 {
+		return fmt.Errorf("downstream call to %s %s has negative count", c.Service, c.Route)
+	}
+	err = c.CountConfigs.validate(fm)
+	if err != nil {
+		return fmt.Errorf("downstream call to %s %s has invalid countConfigs: %v", c.Service, c.Route, err)
+	}
+	if c.Execution != "" && c.Execution != ParallelExecution && c.Execution != SequentialExecution {
+		return fmt.Errorf("downstream call to %s %s has invalid execution %s, must be %s or %s", c.Service, c.Route, c.Execution, ParallelExecution, SequentialExecution)
+	}
+	if c.Protocol != "" && c.Protocol != HTTPProtocol && c.Protocol != GRPCProtocol {
+		return fmt.Errorf("downstream call to %s %s has invalid protocol %s, must be %s or %s", c.Service, c.Route, c.Protocol, HTTPProtocol, GRPCProtocol)
+	}
+	if c.NetworkLatencyMillis < 0 {
+		return fmt.Errorf("downstream call to %s %s has negative networkLatencyMillis", c.Service, c.Route)
+	}
+	switch c.OnError {
+	case "", PropagateErrors, RetryErrors, SwallowErrors:
+	default:
+		return fmt.Errorf("downstream call to %s %s has invalid onError %s, must be %s, %s or %s", c.Service, c.Route, c.OnError, PropagateErrors, RetryErrors, SwallowErrors)
+	}
+	if c.Retries < 0 {
+		return fmt.Errorf("downstream call to %s %s has negative retries", c.Service, c.Route)
+	}
+	if c.Async != nil {
+		if c.OnError == RetryErrors {
+			return fmt.Errorf("async downstream call to %s %s cannot retry errors", c.Service, c.Route)
+		}
+		err = c.Async.validate()
+		if err != nil {
+			return fmt.Errorf("async downstream call to %s %s: %v", c.Service, c.Route, err)
+		}
+	}
 	return nil
 }
 
+// IsAsync returns whether the call is made through a message queue.
+func (c *Call) IsAsync() bool {
+	return c.Async != nil
+}
+
+// SwallowsErrors returns whether a failed call leaves the caller's span unaffected.
+func (c *Call) SwallowsErrors() bool {
+	return c.OnError == SwallowErrors
+}
+
+// GetRetries returns how many times a failed call is retried.
+func (c *Call) GetRetries() int {
+	if c.OnError != RetryErrors {
+		return 0
+	}
+	if c.Retries == 0 {
+		return DefaultRetries
+	}
+	return c.Retries
+}
+
+// IsGRPC returns whether the call is made over gRPC rather than HTTP.
+func (c *Call) IsGRPC() bool {
+	return c.Protocol == GRPCProtocol
+}
+
+// SampleNetworkLatency samples the time a request or response spends on the network.
+func (c *Call) SampleNetworkLatency(random *rand.Rand) int64 {
+	if c.NetworkLatencyMillis <= 0 {
+		return 0
+	}
+	return random.Int63n(c.NetworkLatencyMillis * 1000000)
+}
+
+// ShouldCall samples whether the call is made.
+func (c *Call) ShouldCall(random *rand.Rand) bool {
+	return c.ShouldGenerate() && sample(c.Probability, random)
+}
+
+// IsSequential returns whether the call waits for every call before it to return before starting.
+// Parallel calls start together with the other parallel calls around them.
+func (c *Call) IsSequential() bool {
+	return c.Execution == SequentialExecution
+}
+
 func (r *ServiceRoute) load(route string) error {
 	r.Route = route
+	if r.Semconv != nil {
+		r.Semconv.load()
+	}
 	if r.LatencyConfigs == nil {
 		if r.MaxLatencyMillis == 0 {
 			return fmt.Errorf("route must include maxLatencyMillis or latencyConfigs")
@@ -56,39 +253,14 @@ func (r *ServiceRoute) load(route string) error {
 			return nil
 		}
 	}
-	var hasDefault bool
-	var hasWeights bool
-	for _, cfg := range r.LatencyConfigs {
-		if cfg.Weight != 0 {
-			hasWeights = true
-		}
-
-		err := cfg.loadDurations()
-		if err != nil {
-			return fmt.Errorf("error parsing latencyPercentiles: %v", err)
-		}
-		err = cfg.ValidateFlags()
-		if err != nil {
-			return err
-		}
-		if cfg.IsDefault() {
-			if hasDefault {
-				return fmt.Errorf("latencyConfigs must include exactly one default config (no flag_set or flag_unset)")
-			}
-			hasDefault = true
-		}
-	}
-	if !hasDefault {
-		return fmt.Errorf("latencyConfigs must include exactly one default config (no flag_set or flag_unset)")
-	}
+	return r.LatencyConfigs.load()
+}
 
-	if !hasWeights {
-		// If there are no weights, everything should have the same weight.
-		for _, config := range r.LatencyConfigs {
-			config.Weight = 1
-		}
+func (r *ServiceRoute) GetSpanKind() ptrace.SpanKind {
+	if r.Kind == "" {
+		return ptrace.SpanKindServer
 	}
-	return nil
+	return spanKinds[strings.ToLower(r.Kind)]
 }
 
 func (r *ServiceRoute) SampleLatency(traceID pcommon.TraceID, random *rand.Rand) int64 {
@@ -98,3 +270,25 @@ func (r *ServiceRoute) SampleLatency(traceID pcommon.TraceID, random *rand.Rand)
 		return r.LatencyConfigs.Sample(traceID, random)
 	}
 }
+
+// RenderSpanName returns the route's span name, e.g. GET /product/{id} or $service $route, with its
+// templated variables replaced. It defaults to the route itself.
+func (r *ServiceRoute) RenderSpanName(service string, route string, traceID string, spanID string) string {
+	if r.SpanName == "" {
+		return route
+	}
+	return renderTemplate(r.SpanName, service, route, traceID, spanID)
+}
+
+// SampleSelfTime samples the time the route spends processing after its last downstream call returns.
+func (r *ServiceRoute) SampleSelfTime(random *rand.Rand) int64 {
+	if r.SelfTimeMillis <= 0 {
+		return 0
+	}
+	return random.Int63n(r.SelfTimeMillis * 1000000)
+}
+
+// SampleError returns the error a span of the route fails with, or nil if it succeeds.
+func (r *ServiceRoute) SampleError(random *rand.Rand) *ErrorConfig {
+	return sampleError(r.Errors, random)
+}
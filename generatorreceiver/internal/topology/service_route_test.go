@@ -17,23 +17,138 @@ var routeTestTopology = Topology{
 	},
 }
 
+var eventAfterSpan = 1.5
+
+var errorProbability, invalidErrorProbability = 0.1, 1.5
+
+var callProbability, invalidCallProbability = 0.5, -0.5
+
+var eventProbability, invalidLinkProbability = 0.2, 2.0
+
 var routeTestFrontend = ServiceTier{
 	Routes: map[string]*ServiceRoute{
 		"/cart": {
-			DownstreamCalls:  []Call{{"cartservice", "/GetCart"}}, // valid service and route
+			DownstreamCalls:  []Call{{Service: "cartservice", Route: "/GetCart"}}, // valid service and route
 			MaxLatencyMillis: 500,
 			EmbeddedFlags: flags.EmbeddedFlags{
 				FlagSet:   "someFlag",
 				FlagUnset: "someOtherFlag"},
 		},
 		"/checkout": {
-			DownstreamCalls:  []Call{{"checkoutservice", "/FakeRoute"}}, // valid service, invalid route
+			DownstreamCalls:  []Call{{Service: "checkoutservice", Route: "/FakeRoute"}}, // valid service, invalid route
 			MaxLatencyMillis: 500,
 		},
 		"/badroute": {
-			DownstreamCalls:  []Call{{"nonexistentservice", "/NonexistentRoute"}}, // invalid service & route
+			DownstreamCalls:  []Call{{Service: "nonexistentservice", Route: "/NonexistentRoute"}}, // invalid service & route
+			MaxLatencyMillis: 500,
+		},
+		"/sequential": {
+			DownstreamCalls: []Call{
+				{Service: "checkoutservice", Route: "/GetQuote", Execution: SequentialExecution},
+				{Service: "checkoutservice", Route: "/GetQuote", Execution: ParallelExecution, Protocol: GRPCProtocol, NetworkLatencyMillis: 5},
+			},
+			MaxLatencyMillis: 500,
+			SelfTimeMillis:   100,
+		},
+		"/badexecution": {
+			DownstreamCalls:  []Call{{Service: "checkoutservice", Route: "/GetQuote", Execution: "eventually"}}, // invalid execution
+			MaxLatencyMillis: 500,
+		},
+		"/badprotocol": {
+			DownstreamCalls:  []Call{{Service: "checkoutservice", Route: "/GetQuote", Protocol: "smtp"}}, // invalid protocol
+			MaxLatencyMillis: 500,
+		},
+		"/errors": {
+			DownstreamCalls: []Call{{Service: "checkoutservice", Route: "/GetQuote", OnError: RetryErrors, Retries: 3}},
+			Errors: []ErrorConfig{
+				{Probability: &errorProbability, StatusCode: 500, Exception: &Exception{Type: "TimeoutException"}},
+				{StatusCode: 503, EmbeddedFlags: flags.EmbeddedFlags{FlagSet: "someFlag"}},
+			},
+			MaxLatencyMillis: 500,
+		},
+		"/badonerror": {
+			DownstreamCalls:  []Call{{Service: "checkoutservice", Route: "/GetQuote", OnError: "ignore"}}, // invalid onError
+			MaxLatencyMillis: 500,
+		},
+		"/baderror": {
+			Errors:           []ErrorConfig{{Probability: &invalidErrorProbability}}, // invalid probability
+			MaxLatencyMillis: 500,
+		},
+		"/badexception": {
+			Errors:           []ErrorConfig{{Exception: &Exception{Message: "no type"}}}, // missing exception type
+			MaxLatencyMillis: 500,
+		},
+		"/async": {
+			DownstreamCalls:  []Call{{Service: "checkoutservice", Route: "/GetQuote", Async: &AsyncCall{DestinationKind: QueueDestination, QueueDelayMillis: 100, NewTrace: true}}},
+			MaxLatencyMillis: 500,
+		},
+		"/badasync": {
+			DownstreamCalls:  []Call{{Service: "checkoutservice", Route: "/GetQuote", Async: &AsyncCall{DestinationKind: "mailbox"}}}, // invalid destinationKind
+			MaxLatencyMillis: 500,
+		},
+		"/badasyncretry": {
+			DownstreamCalls:  []Call{{Service: "checkoutservice", Route: "/GetQuote", OnError: RetryErrors, Async: &AsyncCall{}}}, // async calls cannot retry
+			MaxLatencyMillis: 500,
+		},
+		"/flaggedcall": {
+			DownstreamCalls:  []Call{{Service: "checkoutservice", Route: "/GetQuote", Probability: &callProbability, EmbeddedFlags: flags.EmbeddedFlags{FlagSet: "someFlag"}}},
+			MaxLatencyMillis: 500,
+		},
+		"/badcallflag": {
+			DownstreamCalls:  []Call{{Service: "checkoutservice", Route: "/GetQuote", EmbeddedFlags: flags.EmbeddedFlags{FlagUnset: "missingFlag"}}}, // flag does not exist
+			MaxLatencyMillis: 500,
+		},
+		"/badcallprobability": {
+			DownstreamCalls:  []Call{{Service: "checkoutservice", Route: "/GetQuote", Probability: &invalidCallProbability}}, // invalid probability
+			MaxLatencyMillis: 500,
+		},
+		"/events": {
+			Events:           []SpanEvent{{Name: "cache.miss", Probability: &eventProbability, EmbeddedFlags: flags.EmbeddedFlags{FlagSet: "someFlag"}}},
+			Links:            []SpanLink{{PreviousTrace: true}},
+			MaxLatencyMillis: 500,
+		},
+		"/badevent": {
+			Events:           []SpanEvent{{Name: "late", At: &eventAfterSpan}}, // event after the span ends
+			MaxLatencyMillis: 500,
+		},
+		"/badlink": {
+			Links:            []SpanLink{{Probability: &invalidLinkProbability}}, // invalid probability
+			MaxLatencyMillis: 500,
+		},
+		"/named": {
+			SpanName:         "GET /named/{id}",
+			Kind:             "producer",
+			Scope:            &Scope{Name: "io.opentelemetry.kafka-clients-2.6", Version: "1.31.0"},
+			MaxLatencyMillis: 500,
+		},
+		"/badkind": {
+			Kind:             "observer", // invalid span kind
+			MaxLatencyMillis: 500,
+		},
+		"/badscope": {
+			Scope:            &Scope{Version: "1.0.0"}, // scope without name
 			MaxLatencyMillis: 500,
 		},
+		"/semconv": {
+			Semconv:          &Semconv{Type: HTTPSemconv, Method: "POST", StatusCodes: StatusCodes{{Code: 200}, {Code: 404}}},
+			MaxLatencyMillis: 500,
+		},
+		"/badsemconvtype": {
+			Semconv:          &Semconv{Type: "soap"},
+			MaxLatencyMillis: 500,
+		},
+		"/badsemconvcode": {
+			Semconv:          &Semconv{Type: GRPCSemconv, StatusCodes: StatusCodes{{Code: 200}}}, // not a gRPC code
+			MaxLatencyMillis: 500,
+		},
+		"/baddbsemconv": {
+			Semconv:          &Semconv{Type: DBSemconv}, // db without system
+			MaxLatencyMillis: 500,
+		},
+		"/badselftime": {
+			MaxLatencyMillis: 500,
+			SelfTimeMillis:   -1,
+		},
 	},
 }
 
@@ -50,6 +165,7 @@ var routeTestCartService = ServiceTier{
 var routeTestCheckoutService = ServiceTier{
 	Routes: map[string]*ServiceRoute{
 		"/PlaceOrder": {},
+		"/GetQuote":   {MaxLatencyMillis: 100},
 	},
 }
 
@@ -80,6 +196,153 @@ func TestServiceRoute_Validate(t *testing.T) {
 			route:   "/badroute",
 			error:   true,
 		},
+		{
+			name:    "Sequential and parallel downstream calls with self time",
+			service: "frontend",
+			route:   "/sequential",
+			error:   false,
+		},
+		{
+			name:    "Invalid downstream call execution",
+			service: "frontend",
+			route:   "/badexecution",
+			error:   true,
+		},
+		{
+			name:    "Invalid downstream call protocol",
+			service: "frontend",
+			route:   "/badprotocol",
+			error:   true,
+		},
+		{
+			name:    "Errors with retried downstream call",
+			service: "frontend",
+			route:   "/errors",
+			flags:   []string{"someFlag"},
+			error:   false,
+		},
+		{
+			name:    "Invalid downstream call onError",
+			service: "frontend",
+			route:   "/badonerror",
+			error:   true,
+		},
+		{
+			name:    "Invalid error probability",
+			service: "frontend",
+			route:   "/baderror",
+			error:   true,
+		},
+		{
+			name:    "Exception without type",
+			service: "frontend",
+			route:   "/badexception",
+			error:   true,
+		},
+		{
+			name:    "Async downstream call",
+			service: "frontend",
+			route:   "/async",
+			error:   false,
+		},
+		{
+			name:    "Invalid async destinationKind",
+			service: "frontend",
+			route:   "/badasync",
+			error:   true,
+		},
+		{
+			name:    "Async downstream call retrying errors",
+			service: "frontend",
+			route:   "/badasyncretry",
+			error:   true,
+		},
+		{
+			name:    "Flag gated downstream call with probability",
+			service: "frontend",
+			route:   "/flaggedcall",
+			flags:   []string{"someFlag"},
+			error:   false,
+		},
+		{
+			name:    "Downstream call flag does not exist",
+			service: "frontend",
+			route:   "/badcallflag",
+			error:   true,
+		},
+		{
+			name:    "Invalid downstream call probability",
+			service: "frontend",
+			route:   "/badcallprobability",
+			error:   true,
+		},
+		{
+			name:    "Flag gated events and links",
+			service: "frontend",
+			route:   "/events",
+			flags:   []string{"someFlag"},
+			error:   false,
+		},
+		{
+			name:    "Event after the span ends",
+			service: "frontend",
+			route:   "/badevent",
+			error:   true,
+		},
+		{
+			name:    "Invalid link probability",
+			service: "frontend",
+			route:   "/badlink",
+			error:   true,
+		},
+		{
+			name:    "Span name, kind and scope",
+			service: "frontend",
+			route:   "/named",
+			error:   false,
+		},
+		{
+			name:    "Invalid span kind",
+			service: "frontend",
+			route:   "/badkind",
+			error:   true,
+		},
+		{
+			name:    "Scope without name",
+			service: "frontend",
+			route:   "/badscope",
+			error:   true,
+		},
+		{
+			name:    "Semconv profile",
+			service: "frontend",
+			route:   "/semconv",
+			error:   false,
+		},
+		{
+			name:    "Invalid semconv type",
+			service: "frontend",
+			route:   "/badsemconvtype",
+			error:   true,
+		},
+		{
+			name:    "Invalid semconv status code",
+			service: "frontend",
+			route:   "/badsemconvcode",
+			error:   true,
+		},
+		{
+			name:    "DB semconv without system",
+			service: "frontend",
+			route:   "/baddbsemconv",
+			error:   true,
+		},
+		{
+			name:    "Negative selfTimeMillis",
+			service: "frontend",
+			route:   "/badselftime",
+			error:   true,
+		},
 		{
 			name:    "Flag was specified but it does not exist",
 			service: "cartservice",
@@ -105,7 +368,7 @@ func TestServiceRoute_Validate(t *testing.T) {
 			}
 			flags.Manager.LoadFlags(theFlags, zap.NewNop())
 
-			err := r.validate(routeTestTopology)
+			err := r.validate(routeTestTopology, flags.Manager)
 			if err != nil && !tt.error {
 				assert.Fail(t, fmt.Sprintf("did not expect validation error but got: %v", err))
 			}
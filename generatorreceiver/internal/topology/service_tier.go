@@ -2,8 +2,11 @@ package topology
 
 import (
 	"fmt"
+	"time"
 
 	"go.opentelemetry.io/collector/pdata/pcommon"
+
+	"github.com/lightstep/telemetry-generator/generatorreceiver/internal/flags"
 )
 
 type ServiceTier struct {
@@ -12,6 +15,22 @@ type ServiceTier struct {
 	TagSets               []TagSet                 `json:"tagSets" yaml:"tagSets"`
 	ResourceAttributeSets []ResourceAttributeSet   `json:"resourceAttrSets" yaml:"resourceAttrSets"`
 	Metrics               []Metric                 `json:"metrics" yaml:"metrics"`
+	Logs                  []Log                    `json:"logs,omitempty" yaml:"logs,omitempty"`
+	// Interval is how often the service's metrics are generated, unless they set their own.
+	Interval *time.Duration `json:"interval,omitempty" yaml:"interval,omitempty"`
+}
+
+// GetMetricInterval returns how often m is generated: its own interval if set, otherwise the
+// service's, otherwise DefaultMetricTickerPeriod.
+func (st *ServiceTier) GetMetricInterval(m *Metric) time.Duration {
+	switch {
+	case m.Interval != nil:
+		return *m.Interval
+	case st.Interval != nil:
+		return *st.Interval
+	default:
+		return DefaultMetricTickerPeriod
+	}
 }
 
 func (st *ServiceTier) GetTagSet(routeName string, traceID pcommon.TraceID) TagSet {
@@ -41,47 +60,89 @@ func (st *ServiceTier) GetRoute(routeName string) *ServiceRoute {
 	return st.Routes[routeName]
 }
 
-func (st *ServiceTier) Validate(topology Topology) error {
-	for _, m := range st.Metrics {
-		err := m.ValidateFlags()
+func (st *ServiceTier) Validate(topology Topology, fm *flags.FlagManager) error {
+	if problems := st.validate(topology, fm); len(problems) > 0 {
+		return problems[0].Err
+	}
+	return nil
+}
+
+// validate returns every problem with the service, at paths relative to the service.
+func (st *ServiceTier) validate(topology Topology, fm *flags.FlagManager) []Problem {
+	var problems []Problem
+	if st.Interval != nil && *st.Interval <= 0 {
+		problems = append(problems, newProblem(fmt.Errorf("interval of service %s must be positive", st.ServiceName), "interval"))
+	}
+	for i, m := range st.Metrics {
+		err := m.validate(fm)
 		if err != nil {
-			return fmt.Errorf("error with metric %s in service %s: %v", m.Name, st.ServiceName, err)
+			problems = append(problems, newProblem(fmt.Errorf("error with metric %s in service %s: %v", m.Name, st.ServiceName, err), "metrics", i))
 		}
 	}
-	for _, r := range st.Routes {
-		err := r.validate(topology)
+	for i := range st.Logs {
+		err := st.Logs[i].validate(fm)
 		if err != nil {
-			return fmt.Errorf("error with route %s in service %s: %v", r.Route, st.ServiceName, err)
+			problems = append(problems, newProblem(fmt.Errorf("error with logs in service %s: %v", st.ServiceName, err), "logs", i))
 		}
 	}
-	for _, t := range st.TagSets {
-		err := t.ValidateFlags()
+	for _, name := range sortedKeys(st.Routes) {
+		r := st.Routes[name]
+		if r == nil {
+			problems = append(problems, newProblem(fmt.Errorf("route %s in service %s has no definition", name, st.ServiceName), "routes", name))
+			continue
+		}
+		err := r.validate(topology, fm)
 		if err != nil {
-			return fmt.Errorf("error with tagSets in service %s: %v", st.ServiceName, err)
+			problems = append(problems, newProblem(fmt.Errorf("error with route %s in service %s: %v", r.Route, st.ServiceName, err), "routes", name))
+		}
+	}
+	for i, t := range st.TagSets {
+		err := t.ValidateFlags(fm)
+		if err != nil {
+			problems = append(problems, newProblem(fmt.Errorf("error with tagSets in service %s: %v", st.ServiceName, err), "tagSets", i))
 		}
 	}
 	for i := range st.ResourceAttributeSets {
-		err := st.ResourceAttributeSets[i].ValidateFlags()
+		err := st.ResourceAttributeSets[i].ValidateFlags(fm)
 		if err != nil {
-			return fmt.Errorf("error with resourceAttributeSets in service %s: %v", st.ServiceName, err)
+			problems = append(problems, newProblem(fmt.Errorf("error with resourceAttributeSets in service %s: %v", st.ServiceName, err), "resourceAttrSets", i))
+		}
+		if k8s := st.ResourceAttributeSets[i].Kubernetes; k8s != nil {
+			err = k8s.validate()
+			if err != nil {
+				problems = append(problems, newProblem(fmt.Errorf("error with kubernetes in service %s: %v", st.ServiceName, err), "resourceAttrSets", i, "kubernetes"))
+			}
 		}
 	}
-	return nil
+	return problems
 }
 
 func (st *ServiceTier) load(service string) error {
+	if problems := st.loadAll(service); len(problems) > 0 {
+		return problems[0].Err
+	}
+	return nil
+}
+
+// loadAll loads the service like load, but carries on past errors and returns all of them.
+func (st *ServiceTier) loadAll(service string) []Problem {
+	var problems []Problem
 	st.ServiceName = service
 	for i := range st.TagSets {
 		err := st.TagSets[i].loadCsvTags()
 		if err != nil {
-			return fmt.Errorf("error loading csv tags for service %s: %v", service, err)
+			problems = append(problems, newProblem(fmt.Errorf("error loading csv tags for service %s: %v", service, err), "tagSets", i))
 		}
 	}
-	for name, route := range st.Routes {
+	for _, name := range sortedKeys(st.Routes) {
+		route := st.Routes[name]
+		if route == nil {
+			continue // reported by validate
+		}
 		err := route.load(name)
 		if err != nil {
-			return fmt.Errorf("error loading route %s for service %s: %v", name, service, err)
+			problems = append(problems, newProblem(fmt.Errorf("error loading route %s for service %s: %v", name, service, err), "routes", name))
 		}
 	}
-	return nil
+	return problems
 }
@@ -0,0 +1,76 @@
+package topology
+
+import (
+	"fmt"
+	"math/rand"
+
+	"github.com/lightstep/telemetry-generator/generatorreceiver/internal/flags"
+)
+
+// SpanEvent describes an event added to a route's spans.
+type SpanEvent struct {
+	Name       string `json:"name" yaml:"name"`
+	Attributes TagMap `json:"attributes,omitempty" yaml:"attributes,omitempty"`
+	// At is when the event happens, as a fraction of the span's duration from its start.
+	// Events without At happen at a random time during the span.
+	At *float64 `json:"at,omitempty" yaml:"at,omitempty"`
+	// Probability is the chance of a span having the event, see sample.
+	Probability         *float64 `json:"probability,omitempty" yaml:"probability,omitempty"`
+	flags.EmbeddedFlags `json:",inline" yaml:",inline"`
+}
+
+// SpanLink describes a link added to a route's spans. Links point to a random span in another
+// trace, or with PreviousTrace, to the root span of the previous trace of the same root route.
+type SpanLink struct {
+	Attributes    TagMap `json:"attributes,omitempty" yaml:"attributes,omitempty"`
+	PreviousTrace bool   `json:"previousTrace,omitempty" yaml:"previousTrace,omitempty"`
+	// Probability is the chance of a span having the link, see sample.
+	Probability         *float64 `json:"probability,omitempty" yaml:"probability,omitempty"`
+	flags.EmbeddedFlags `json:",inline" yaml:",inline"`
+}
+
+// ShouldAdd samples whether a span has the event.
+func (e *SpanEvent) ShouldAdd(random *rand.Rand) bool {
+	return e.ShouldGenerate() && sample(e.Probability, random)
+}
+
+// SampleAt returns when the event happens, as a fraction of the span's duration.
+func (e *SpanEvent) SampleAt(random *rand.Rand) float64 {
+	if e.At == nil {
+		return random.Float64()
+	}
+	return *e.At
+}
+
+// ShouldAdd samples whether a span has the link.
+func (l *SpanLink) ShouldAdd(random *rand.Rand) bool {
+	return l.ShouldGenerate() && sample(l.Probability, random)
+}
+
+func (e *SpanEvent) validate(fm *flags.FlagManager) error {
+	err := e.ValidateFlags(fm)
+	if err != nil {
+		return err
+	}
+	if e.Name == "" {
+		return fmt.Errorf("events must have a name")
+	}
+	if e.At != nil && (*e.At < 0 || *e.At > 1) {
+		return fmt.Errorf("event %s must be at a fraction between 0 and 1 of the span", e.Name)
+	}
+	if !validProbability(e.Probability) {
+		return fmt.Errorf("event %s probability must be between 0 and 1", e.Name)
+	}
+	return nil
+}
+
+func (l *SpanLink) validate(fm *flags.FlagManager) error {
+	err := l.ValidateFlags(fm)
+	if err != nil {
+		return err
+	}
+	if !validProbability(l.Probability) {
+		return fmt.Errorf("link probability must be between 0 and 1")
+	}
+	return nil
+}
@@ -2,6 +2,7 @@ package topology
 
 import (
 	"math/rand"
+	"sort"
 )
 
 type TagGenerator struct {
@@ -46,7 +47,13 @@ func (t *TagGenerator) GetTags() map[string]string {
 
 func (t *TagGenerator) GetRefreshedTags() map[string]string{
 	rtg := &RandomTagGenerator{random: t.Random}
+	// refresh in key order, so the same random source always refreshes the same tags
+	keys := make([]string, 0, len(t.tags))
 	for k := range t.tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
 		n := t.Random.Intn(100)
 
 		if n < t.ValueVariability {
@@ -4,13 +4,17 @@ import (
 	"fmt"
 	"go.opentelemetry.io/collector/pdata/pcommon"
 	"math/rand"
+	"sort"
 	"strconv"
 )
 
 type TagMap map[string]interface{}
 
+// InsertTags puts the tags into attr in key order, so the same random source always picks the
+// same values.
 func (tm *TagMap) InsertTags(attr *pcommon.Map, random *rand.Rand) {
-	for key, val := range *tm {
+	for _, key := range tm.keys() {
+		val := (*tm)[key]
 		switch val := val.(type) {
 		case float64:
 			attr.PutDouble(key, val)
@@ -31,3 +35,12 @@ func (tm *TagMap) InsertTags(attr *pcommon.Map, random *rand.Rand) {
 		}
 	}
 }
+
+func (tm *TagMap) keys() []string {
+	keys := make([]string, 0, len(*tm))
+	for key := range *tm {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -3,16 +3,34 @@ package topology
 import (
 	"errors"
 	"fmt"
+
+	"github.com/lightstep/telemetry-generator/generatorreceiver/internal/flags"
 )
 
 type Topology struct {
-	Services map[string]*ServiceTier `json:"services" yaml:"services"`
+	Services     map[string]*ServiceTier `json:"services" yaml:"services"`
+	Dependencies map[string]*Dependency  `json:"dependencies,omitempty" yaml:"dependencies,omitempty"`
 }
 
 func (t *Topology) GetServiceTier(serviceName string) *ServiceTier {
 	return t.Services[serviceName]
 }
 
+// GetDependency returns the dependency named name, or nil if there is none.
+func (t *Topology) GetDependency(name string) *Dependency {
+	return t.Dependencies[name]
+}
+
+func (t *Topology) ValidateDependencies(fm *flags.FlagManager) error {
+	for _, d := range t.Dependencies {
+		err := d.validate(*t, fm)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (t *Topology) ValidateServiceGraph(rootRoutes []RootRoute) error {
 	for _, rr := range rootRoutes {
 		err := t.validateDownstreamCalls(rr.Service, rr.Route)
@@ -39,6 +57,10 @@ func (t *Topology) traverseServiceGraph(service string, route string, seenCalls
 	// already validated existence of all services/routes, so ^ is safe
 	seenCalls[service+route] = true
 	for _, c := range downstreamCalls {
+		if t.GetDependency(c.Service) != nil {
+			// dependencies are leaves, they make no calls
+			continue
+		}
 		if seenCalls[c.Service+c.Route] {
 			return errors.New(printServiceCycle(orderedCalls, c.Service+c.Route))
 		}
@@ -68,5 +90,11 @@ func (t *Topology) Load() error {
 			return err
 		}
 	}
+	for name, dependency := range t.Dependencies {
+		err := dependency.load(name)
+		if err != nil {
+			return fmt.Errorf("error loading dependency %s: %v", name, err)
+		}
+	}
 	return nil
 }
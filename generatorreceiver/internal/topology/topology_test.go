@@ -11,12 +11,12 @@ var topoTestFrontend = ServiceTier{
 	Routes: map[string]*ServiceRoute{
 		"/product": {
 			DownstreamCalls: []Call{
-				{"productcatalogservice", "/GetProducts"},
-				{"recommendationservice", "/GetRecommendations"},
+				{Service: "productcatalogservice", Route: "/GetProducts"},
+				{Service: "recommendationservice", Route: "/GetRecommendations"},
 			},
 		},
 		"/cart": {
-			DownstreamCalls: []Call{{"recommendationservice", "/GetRecommendations"}},
+			DownstreamCalls: []Call{{Service: "recommendationservice", Route: "/GetRecommendations"}},
 		},
 	},
 }
@@ -27,13 +27,13 @@ var topoTestCatalogService = ServiceTier{
 
 var topoTestCyclicalCatalogService = ServiceTier{
 	Routes: map[string]*ServiceRoute{
-		"/GetProducts": {DownstreamCalls: []Call{{"frontend", "/cart"}}},
+		"/GetProducts": {DownstreamCalls: []Call{{Service: "frontend", Route: "/cart"}}},
 	},
 }
 
 var topoTestRecommendationService = ServiceTier{
 	Routes: map[string]*ServiceRoute{
-		"/GetRecommendations": {DownstreamCalls: []Call{{"productcatalogservice", "/GetProducts"}}},
+		"/GetRecommendations": {DownstreamCalls: []Call{{Service: "productcatalogservice", Route: "/GetProducts"}}},
 	},
 }
 
@@ -0,0 +1,118 @@
+package generatorreceiver
+
+import (
+	"bytes"
+	"errors"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/lightstep/telemetry-generator/generatorreceiver/internal/flags"
+	"github.com/lightstep/telemetry-generator/generatorreceiver/internal/topology"
+)
+
+// Diagnostic is a problem found in a topology by ValidateTopology.
+type Diagnostic = topology.Diagnostic
+
+// ValidateTopology reads the topology file at topoPath and returns every problem found in it,
+// errors first, then warnings, each in the order of their lines. The error is only set when the
+// file cannot be read.
+func ValidateTopology(topoPath string) ([]Diagnostic, error) {
+	topoBytes, err := readTopoFile(topoPath)
+	if err != nil {
+		return nil, err
+	}
+	return lintTopo(topoBytes), nil
+}
+
+// HasErrors reports whether any of diagnostics is an error rather than a warning.
+func HasErrors(diagnostics []Diagnostic) bool {
+	for _, d := range diagnostics {
+		if d.Severity == topology.SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+var yamlLineRegexp = regexp.MustCompile(`line (\d+): `)
+
+// lintTopo lints the topology in topoBytes against its own flags, like a reloaded topology is
+// validated. The loaded flags are left alone.
+func lintTopo(topoBytes []byte) []Diagnostic {
+	topoFile, err := parseTopo(topoBytes)
+	if err != nil {
+		return []Diagnostic{yamlDiagnostic(topology.SeverityError, err.Error())}
+	}
+	var root yaml.Node
+	_ = yaml.Unmarshal(topoBytes, &root) // parses, since parseTopo did
+
+	diagnostics := topoFile.Lint(flags.NewFlagManager(topoFile.Flags...))
+	for i := range diagnostics {
+		diagnostics[i].Line = nodeLine(&root, diagnostics[i].Path)
+	}
+
+	// fields that the receiver doesn't know are ignored, which is most likely a typo
+	decoder := yaml.NewDecoder(bytes.NewReader(topoBytes))
+	decoder.KnownFields(true)
+	var typeErr *yaml.TypeError
+	if err := decoder.Decode(&topology.File{}); errors.As(err, &typeErr) {
+		for _, msg := range typeErr.Errors {
+			diagnostics = append(diagnostics, yamlDiagnostic(topology.SeverityWarning, msg))
+		}
+	}
+
+	sort.SliceStable(diagnostics, func(i, j int) bool {
+		if diagnostics[i].Severity != diagnostics[j].Severity {
+			return diagnostics[i].Severity == topology.SeverityError
+		}
+		return diagnostics[i].Line < diagnostics[j].Line
+	})
+	return diagnostics
+}
+
+// yamlDiagnostic turns an error message of the yaml decoder into a diagnostic, taking the line
+// from the message.
+func yamlDiagnostic(severity topology.Severity, msg string) Diagnostic {
+	d := Diagnostic{Severity: severity, Message: msg}
+	if m := yamlLineRegexp.FindStringSubmatchIndex(msg); m != nil {
+		d.Line, _ = strconv.Atoi(msg[m[2]:m[3]])
+		d.Message = msg[m[1]:]
+	}
+	return d
+}
+
+// nodeLine returns the line of the node at path in the YAML document root, or the line of the
+// closest ancestor that exists in the document.
+func nodeLine(root *yaml.Node, path []string) int {
+	if len(root.Content) == 0 {
+		return 0
+	}
+	node := root.Content[0]
+	line := node.Line
+	for _, segment := range path {
+		var next *yaml.Node
+		switch node.Kind {
+		case yaml.MappingNode:
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				if node.Content[i].Value == segment {
+					line = node.Content[i].Line
+					next = node.Content[i+1]
+					break
+				}
+			}
+		case yaml.SequenceNode:
+			if i, err := strconv.Atoi(segment); err == nil && i < len(node.Content) {
+				next = node.Content[i]
+				line = next.Line
+			}
+		}
+		if next == nil {
+			break
+		}
+		node = next
+	}
+	return line
+}
@@ -0,0 +1,76 @@
+package generatorreceiver
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lightstep/telemetry-generator/generatorreceiver/internal/flags"
+	"github.com/lightstep/telemetry-generator/generatorreceiver/internal/topology"
+)
+
+const lintTestTopo = `
+topology:
+  services:
+    frontend:
+      routes:
+        /product:
+          downstreamCalls:
+            - service: cartservice
+              route: /GetCart
+          maxLatencyMillis: 100
+        /unused:
+          maxLatencyMilis: 100
+flags:
+  - name: unused
+rootRoutes:
+  - service: frontend
+    route: /product
+    tracesPerHour: 100
+`
+
+func TestLintTopo(t *testing.T) {
+	flags.Manager.Clear()
+	var lines []int
+	var severities []topology.Severity
+	diagnostics := lintTopo([]byte(lintTestTopo))
+	for _, d := range diagnostics {
+		lines = append(lines, d.Line)
+		severities = append(severities, d.Severity)
+	}
+	// the missing cartservice and the route without a latency, then the never called route, the
+	// misspelled field and the unused flag
+	require.Equal(t, []int{6, 11, 11, 12, 14}, lines)
+	require.Equal(t, []topology.Severity{"error", "error", "warning", "warning", "warning"}, severities)
+	require.True(t, HasErrors(diagnostics))
+	require.Contains(t, diagnostics[3].Message, "maxLatencyMilis")
+	require.Zero(t, flags.Manager.FlagCount(), "the topology's flags are only loaded while linting")
+
+	diagnostics = lintTopo([]byte("topology:\n  services: [\n"))
+	require.Len(t, diagnostics, 1)
+	require.Equal(t, topology.SeverityError, diagnostics[0].Severity)
+	require.NotZero(t, diagnostics[0].Line)
+}
+
+func TestHTTPServer_ValidateTopology(t *testing.T) {
+	flags.Manager.Clear()
+	h := &httpServer{receiver: &generatorReceiver{}}
+
+	rec := httptest.NewRecorder()
+	h.validateTopology(rec, httptest.NewRequest(http.MethodPost, "/api/v1/topology/validate", strings.NewReader(lintTestTopo)))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var resp validateHttpResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.False(t, resp.Valid)
+	require.Len(t, resp.Diagnostics, 5)
+	// the topology is linted against its own flags, not the loaded ones
+	require.Empty(t, flags.Manager.GetFlags())
+
+	rec = httptest.NewRecorder()
+	h.validateTopology(rec, httptest.NewRequest(http.MethodGet, "/api/v1/topology/validate", nil))
+	require.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
@@ -0,0 +1,277 @@
+package generatorreceiver
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+
+	"github.com/lightstep/telemetry-generator/generatorreceiver/internal/clock"
+	"github.com/lightstep/telemetry-generator/generatorreceiver/internal/flags"
+	"github.com/lightstep/telemetry-generator/generatorreceiver/internal/topology"
+)
+
+// runningGenerator is a trace or metric generator that generates once every period, driven by a
+// ticker in its own go routine, or by the backfill. Generators with an interval function tick
+// right away and then wait for as long as it returns before each tick instead, e.g. root routes
+// with a shaped rate.
+type runningGenerator struct {
+	period   time.Duration
+	interval func() time.Duration // nil for a fixed period
+	tick     func()
+	ticker   *time.Ticker // nil unless started
+	done     chan struct{}
+	// how late the last tick started, in nanoseconds of wall time, see getLag
+	lag int64
+	// aligned generators tick on the boundaries of their period on the receiver's clock, e.g.
+	// on the minute, rather than a period after they started, see nextTick
+	aligned bool
+}
+
+func newRunningGenerator(period time.Duration, tick func()) *runningGenerator {
+	return &runningGenerator{period: period, tick: tick, done: make(chan struct{})}
+}
+
+// next returns the time until the generator's next tick.
+func (r *runningGenerator) next() time.Duration {
+	if r.interval == nil {
+		return r.period
+	}
+	return r.interval()
+}
+
+// firstTick returns when a generator started at now ticks first while backfilling: right away,
+// or on the next boundary of its period if it is aligned.
+func (r *runningGenerator) firstTick(now time.Time) time.Time {
+	if r.aligned && !now.Equal(now.Truncate(r.period)) {
+		return r.nextTick(now)
+	}
+	return now
+}
+
+// nextTick returns when the generator ticks after a tick at now.
+func (r *runningGenerator) nextTick(now time.Time) time.Time {
+	if r.aligned {
+		return now.Truncate(r.period).Add(r.period)
+	}
+	return now.Add(r.next())
+}
+
+// getLag returns how late the generator's last tick started compared to its schedule, e.g.
+// because the previous tick took longer than the time between ticks.
+func (r *runningGenerator) getLag() time.Duration {
+	return time.Duration(atomic.LoadInt64(&r.lag))
+}
+
+func (r *runningGenerator) setLag(lag time.Duration) {
+	if lag < 0 {
+		lag = 0
+	}
+	atomic.StoreInt64(&r.lag, int64(lag))
+}
+
+// start generates on a ticker until the generator is stopped. The ticker runs speed times as
+// fast as the period, see ClockConfig.
+func (r *runningGenerator) start(speed float64) {
+	if r.aligned {
+		go r.runAligned(speed)
+		return
+	}
+	if r.interval != nil {
+		go r.runIntervals(speed)
+		return
+	}
+	r.ticker = time.NewTicker(time.Duration(float64(r.period) / speed))
+	go func() {
+		for {
+			select {
+			case <-r.done:
+				return
+			case scheduled := <-r.ticker.C:
+				r.setLag(time.Since(scheduled))
+				r.tick()
+			}
+		}
+	}()
+}
+
+// runIntervals ticks right away, like the backfill does, and then after each interval. Each
+// interval starts when the previous tick was due rather than when it ended, so the generator
+// keeps to its rate when ticks take a while, and catches up on the ticks it missed when it falls
+// behind.
+func (r *runningGenerator) runIntervals(speed float64) {
+	due := time.Now()
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-r.done:
+			return
+		case <-timer.C:
+			r.setLag(time.Since(due))
+			r.tick()
+			due = due.Add(time.Duration(float64(r.next()) / speed))
+			timer.Reset(time.Until(due))
+		}
+	}
+}
+
+// runAligned ticks on the boundaries of the period on the receiver's clock, skipping those it
+// missed when a tick took longer than the period.
+func (r *runningGenerator) runAligned(speed float64) {
+	until := func(t time.Time) time.Duration {
+		return time.Duration(float64(t.Sub(clock.Now())) / speed)
+	}
+	due := r.nextTick(clock.Now())
+	timer := time.NewTimer(until(due))
+	defer timer.Stop()
+	for {
+		select {
+		case <-r.done:
+			return
+		case <-timer.C:
+			if wait := until(due); wait > 0 {
+				// the timer and the receiver's clock may disagree slightly
+				timer.Reset(wait)
+				continue
+			}
+			r.setLag(-until(due))
+			r.tick()
+			due = r.nextTick(due)
+			if now := clock.Now(); due.Before(now) {
+				due = r.nextTick(now)
+			}
+			timer.Reset(until(due))
+		}
+	}
+}
+
+func (r *runningGenerator) stop() {
+	if r.ticker != nil {
+		r.ticker.Stop()
+	}
+	close(r.done)
+}
+
+// watchTopoFile reloads the topology whenever the contents of the topology file change.
+func (g *generatorReceiver) watchTopoFile(done chan struct{}, last []byte) {
+	ticker := time.NewTicker(g.reloadInterval)
+	defer ticker.Stop()
+	g.logger.Info("watching topo file for changes", zap.String("path", g.topoPath), zap.Duration("interval", g.reloadInterval))
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			topoBytes, err := readTopoFile(g.topoPath)
+			if err != nil {
+				g.logger.Error("could not read topo file", zap.Error(err))
+				continue
+			}
+			if bytes.Equal(topoBytes, last) {
+				continue
+			}
+			last = topoBytes
+			_ = g.reloadTopo(topoBytes)
+		}
+	}
+}
+
+func (g *generatorReceiver) reloadTopo(topoBytes []byte) error {
+	err := g.applyTopo(topoBytes)
+	if err != nil {
+		g.logger.Error("could not reload topo, keeping the current one", zap.Error(err))
+		return err
+	}
+	g.logger.Info("reloaded topo", zap.Int("flag_count", flags.Manager.FlagCount()))
+	return nil
+}
+
+func (g *generatorReceiver) serviceUnchanged(name string, fingerprints map[string]string) bool {
+	previous, ok := g.serviceFingerprints[name]
+	return ok && fingerprints[name] == previous
+}
+
+// serviceFingerprints identifies the configuration of each service and dependency so reloads can
+// tell which changed. Those that cannot be fingerprinted are left out and always count as changed.
+func serviceFingerprints(topoFile *topology.File) map[string]string {
+	fingerprints := make(map[string]string, len(topoFile.Topology.Services)+len(topoFile.Topology.Dependencies))
+	for name, s := range topoFile.Topology.Services {
+		fingerprint, err := fingerprint(s, topoFile.Config)
+		if err != nil {
+			continue
+		}
+		fingerprints[name] = fingerprint
+	}
+	for name, d := range topoFile.Topology.Dependencies {
+		fingerprint, err := fingerprint(d)
+		if err != nil {
+			continue
+		}
+		fingerprints[name] = fingerprint
+	}
+	return fingerprints
+}
+
+type rootRouteKey struct {
+	fingerprint string
+	index       int
+}
+
+// rootRouteFingerprints identifies each root route by its own configuration and that of every
+// service its traces go through, so that only root routes affected by a reload are restarted.
+func rootRouteFingerprints(topoFile *topology.File, serviceFingerprints map[string]string) []rootRouteKey {
+	keys := make([]rootRouteKey, 0, len(topoFile.RootRoutes))
+	seen := make(map[string]int)
+	for i, rr := range topoFile.RootRoutes {
+		services := make(map[string]bool)
+		reachableServices(topoFile.Topology, rr.Service, rr.Route, services)
+
+		// the yaml encoding, unlike %v, doesn't depend on the addresses of pointer fields
+		rootRouteFingerprint, err := fingerprint(rr)
+		if err != nil {
+			rootRouteFingerprint = fmt.Sprintf("%p", topoFile)
+		}
+		parts := []string{rootRouteFingerprint}
+		for service := range services {
+			serviceFingerprint, ok := serviceFingerprints[service]
+			if !ok {
+				// unique to this load, so the root route is always restarted.
+				serviceFingerprint = fmt.Sprintf("%p", topoFile)
+			}
+			parts = append(parts, service+"="+serviceFingerprint)
+		}
+		sort.Strings(parts[1:])
+
+		key, _ := fingerprint(parts)
+		// identical root routes get their own generator each.
+		seen[key]++
+		keys = append(keys, rootRouteKey{fingerprint: fmt.Sprintf("%s#%d", key, seen[key]), index: i})
+	}
+	return keys
+}
+
+func reachableServices(t *topology.Topology, service string, route string, services map[string]bool) {
+	services[service] = true
+	if t.GetDependency(service) != nil {
+		return
+	}
+	for _, c := range t.GetServiceTier(service).GetRoute(route).DownstreamCalls {
+		reachableServices(t, c.Service, c.Route, services)
+	}
+}
+
+func fingerprint(values ...interface{}) (string, error) {
+	b, err := yaml.Marshal(values)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,125 @@
+package generatorreceiver
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.uber.org/zap"
+
+	"github.com/lightstep/telemetry-generator/generatorreceiver/internal/clock"
+	"github.com/lightstep/telemetry-generator/generatorreceiver/internal/flags"
+)
+
+// DefaultRenderTracesWindow is how much time RenderTraces generates at most, in case the root
+// routes generate fewer traces than requested.
+const DefaultRenderTracesWindow = 24 * time.Hour
+
+// RenderOptions configure offline generation, see RenderTraces and RenderMetrics.
+type RenderOptions struct {
+	// Traces is the number of traces RenderTraces generates, across all root routes.
+	Traces int
+	// Window is the time range RenderMetrics generates, and the most RenderTraces generates.
+	Window time.Duration
+	// Start is the time the simulated clock starts at.
+	Start time.Time
+	// Seed seeds all random generation, see Config.Seed.
+	Seed int64
+}
+
+// RenderTraces generates traces of the topology at topoPath without running a collector. The
+// topology is loaded and validated like the receiver's, then generated as a backfill starting at
+// options.Start, until options.Traces traces have been generated.
+func RenderTraces(topoPath string, options RenderOptions, logger *zap.Logger) (ptrace.Traces, error) {
+	if options.Traces <= 0 {
+		return ptrace.Traces{}, fmt.Errorf("the number of traces must be positive")
+	}
+	if options.Window == 0 {
+		options.Window = DefaultRenderTracesWindow
+	}
+
+	traces := ptrace.NewTraces()
+	done := make(chan struct{})
+	seen := make(map[pcommon.TraceID]bool)
+	traceConsumer, err := consumer.NewTraces(func(_ context.Context, td ptrace.Traces) error {
+		if len(seen) == options.Traces {
+			return nil
+		}
+		// at high rates, a batch holds several traces, of which only the first few may be needed.
+		td.ResourceSpans().RemoveIf(func(rs ptrace.ResourceSpans) bool {
+			for i := 0; i < rs.ScopeSpans().Len(); i++ {
+				spans := rs.ScopeSpans().At(i).Spans()
+				for j := 0; j < spans.Len(); j++ {
+					traceID := spans.At(j).TraceID()
+					if !seen[traceID] && len(seen) == options.Traces {
+						return true
+					}
+					seen[traceID] = true
+				}
+			}
+			return false
+		})
+		td.ResourceSpans().MoveAndAppendTo(traces.ResourceSpans())
+		if len(seen) == options.Traces {
+			close(done)
+		}
+		return nil
+	})
+	if err != nil {
+		return traces, err
+	}
+
+	g := &generatorReceiver{logger: logger, randomSeed: options.Seed, traceConsumer: traceConsumer}
+	err = g.render(topoPath, options, done)
+	return traces, err
+}
+
+// RenderMetrics generates the metrics of the topology at topoPath for options.Window after
+// options.Start, without running a collector. See RenderTraces.
+func RenderMetrics(topoPath string, options RenderOptions, logger *zap.Logger) (pmetric.Metrics, error) {
+	if options.Window <= 0 {
+		return pmetric.Metrics{}, fmt.Errorf("the window must be positive")
+	}
+
+	metrics := pmetric.NewMetrics()
+	metricConsumer, err := consumer.NewMetrics(func(_ context.Context, md pmetric.Metrics) error {
+		md.ResourceMetrics().MoveAndAppendTo(metrics.ResourceMetrics())
+		return nil
+	})
+	if err != nil {
+		return metrics, err
+	}
+
+	g := &generatorReceiver{logger: logger, randomSeed: options.Seed, metricConsumer: metricConsumer}
+	err = g.render(topoPath, options, make(chan struct{}))
+	return metrics, err
+}
+
+// render runs the backfill of options' time range with the receiver's consumers, until the range
+// ends or done is closed.
+func (g *generatorReceiver) render(topoPath string, options RenderOptions, done chan struct{}) error {
+	topoBytes, err := readTopoFile(topoPath)
+	if err != nil {
+		return fmt.Errorf("could not load topo file: %w", err)
+	}
+
+	g.started = true
+	g.backfillClock, g.backfillEnd = clock.NewSimulated(options.Start), options.Start.Add(options.Window)
+	restoreClock := clock.Set(g.backfillClock)
+	err = g.applyTopo(topoBytes)
+	if err != nil {
+		restoreClock()
+		return err
+	}
+	g.runBackfill(done, restoreClock)
+
+	// nothing runs cron schedules once rendering is done
+	for _, f := range flags.Manager.GetFlags() {
+		f.Teardown()
+	}
+	return nil
+}
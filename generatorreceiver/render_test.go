@@ -0,0 +1,77 @@
+package generatorreceiver
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.uber.org/zap"
+)
+
+func TestRenderTraces(t *testing.T) {
+	topoPath := filepath.Join(t.TempDir(), "topo.yaml")
+	require.NoError(t, os.WriteFile(topoPath, reloadTestTopoWithLatency("200"), 0600))
+	options := RenderOptions{Traces: 25, Start: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC), Seed: 1}
+
+	render := func() []byte {
+		td, err := RenderTraces(topoPath, options, zap.NewNop())
+		require.NoError(t, err)
+		traceIDs := map[string]bool{}
+		for i := 0; i < td.ResourceSpans().Len(); i++ {
+			span := td.ResourceSpans().At(i).ScopeSpans().At(0).Spans().At(0)
+			traceIDs[span.TraceID().String()] = true
+			require.False(t, span.StartTimestamp().AsTime().Before(options.Start))
+		}
+		require.Len(t, traceIDs, 25)
+
+		bytes, err := (&ptrace.ProtoMarshaler{}).MarshalTraces(td)
+		require.NoError(t, err)
+		return bytes
+	}
+	require.Equal(t, render(), render())
+
+	_, err := RenderTraces(topoPath, RenderOptions{}, zap.NewNop())
+	require.Error(t, err)
+	_, err = RenderTraces(filepath.Join(t.TempDir(), "missing.yaml"), options, zap.NewNop())
+	require.Error(t, err)
+}
+
+func TestRenderTraces_RateShape(t *testing.T) {
+	topo := strings.Replace(string(reloadTestTopoWithLatency("200")), "    tracesPerHour: 3600\n",
+		"    tracesPerHour: 3600\n    rateShape:\n      shape: sine\n      period: 1h\n      offset: 10m\n", 1)
+	topoPath := filepath.Join(t.TempDir(), "topo.yaml")
+	require.NoError(t, os.WriteFile(topoPath, []byte(topo), 0600))
+	options := RenderOptions{Traces: 25, Start: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC), Seed: 1}
+
+	render := func() []byte {
+		td, err := RenderTraces(topoPath, options, zap.NewNop())
+		require.NoError(t, err)
+		bytes, err := (&ptrace.ProtoMarshaler{}).MarshalTraces(td)
+		require.NoError(t, err)
+		return bytes
+	}
+	require.Equal(t, render(), render())
+}
+
+func TestRenderMetrics(t *testing.T) {
+	topoPath := filepath.Join(t.TempDir(), "topo.yaml")
+	require.NoError(t, os.WriteFile(topoPath, reloadTestTopoWithLatency("200"), 0600))
+	options := RenderOptions{Window: time.Minute, Start: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC), Seed: 1}
+
+	render := func() []byte {
+		md, err := RenderMetrics(topoPath, options, zap.NewNop())
+		require.NoError(t, err)
+		// the checkoutservice's metric is reported every 15s
+		require.Equal(t, 4, md.DataPointCount())
+
+		bytes, err := (&pmetric.ProtoMarshaler{}).MarshalMetrics(md)
+		require.NoError(t, err)
+		return bytes
+	}
+	require.Equal(t, render(), render())
+}
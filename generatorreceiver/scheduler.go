@@ -0,0 +1,109 @@
+package generatorreceiver
+
+import (
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/lightstep/telemetry-generator/generatorreceiver/internal/clock"
+	"github.com/lightstep/telemetry-generator/generatorreceiver/internal/topology"
+)
+
+const (
+	// minTraceInterval is the shortest time between the ticks of a trace generator. Root routes
+	// with a higher rate generate a batch of the traces that arrived since the last tick instead.
+	minTraceInterval = 10 * time.Millisecond
+	// idleRateInterval is how long a root route waits before checking its rate again while the
+	// rate is zero.
+	idleRateInterval = time.Second
+)
+
+// rateScheduler schedules the traces of a root route, from a few a day up to tens of thousands a
+// second. Below one trace per minTraceInterval, each tick generates a single trace and the next
+// tick is scheduled for the next arrival. Above that, ticks happen every minTraceInterval and
+// each generates the batch of traces that arrived since the previous tick.
+type rateScheduler struct {
+	rootRoute topology.RootRoute
+	random    *rand.Rand
+
+	batching bool
+	last     time.Time // time of the previous tick
+	credit   float64   // fraction of a trace carried over between uniform batches
+}
+
+func newRateScheduler(rootRoute topology.RootRoute, random *rand.Rand) *rateScheduler {
+	return &rateScheduler{rootRoute: rootRoute, random: random, last: clock.Now()}
+}
+
+// rate returns the number of traces per nanosecond at now.
+func (s *rateScheduler) rate(now time.Time) float64 {
+	return s.rootRoute.TracesPerHour * s.rootRoute.RateFactor(now) / float64(time.Hour)
+}
+
+// period returns the mean time between traces at the route's base rate, or minTraceInterval
+// if that is longer.
+func (s *rateScheduler) period() time.Duration {
+	interval := float64(time.Hour) / s.rootRoute.TracesPerHour
+	return durationOf(math.Max(float64(minTraceInterval), interval))
+}
+
+// next returns the time until the next tick.
+func (s *rateScheduler) next() time.Duration {
+	perHour := s.rootRoute.TracesPerHour * s.rootRoute.RateFactor(clock.Now())
+	if perHour <= 0 {
+		s.batching = false
+		return idleRateInterval
+	}
+	interval := float64(time.Hour) / perHour
+	s.batching = interval < float64(minTraceInterval)
+	if s.batching {
+		return minTraceInterval
+	}
+	if s.rootRoute.IsPoisson() {
+		interval *= s.random.ExpFloat64()
+	}
+	return durationOf(interval)
+}
+
+// arrivals returns the start times of the traces to generate on the tick at now. The first tick
+// happens when the scheduler is created, before next decides whether to batch, so it generates
+// the first trace right away.
+func (s *rateScheduler) arrivals(now time.Time) []time.Time {
+	from := s.last
+	s.last = now
+	rate := s.rate(now)
+	if rate <= 0 {
+		s.credit = 0
+		return nil
+	}
+	if !s.batching {
+		return []time.Time{now}
+	}
+
+	window := float64(now.Sub(from))
+	var offsets []float64
+	if s.rootRoute.IsPoisson() {
+		// arrivals are memoryless, so the first one of each window is sampled from its start.
+		for t := s.random.ExpFloat64() / rate; t < window; t += s.random.ExpFloat64() / rate {
+			offsets = append(offsets, t)
+		}
+	} else {
+		s.credit += rate * window
+		n := math.Floor(s.credit)
+		s.credit -= n
+		for i := 0; i < int(n); i++ {
+			offsets = append(offsets, window*float64(i+1)/n)
+		}
+	}
+
+	times := make([]time.Time, len(offsets))
+	for i, offset := range offsets {
+		times[i] = from.Add(time.Duration(offset))
+	}
+	return times
+}
+
+// durationOf rounds ns to a duration, capped well below the duration that would overflow.
+func durationOf(ns float64) time.Duration {
+	return time.Duration(math.Round(math.Min(ns, math.MaxInt64/2)))
+}
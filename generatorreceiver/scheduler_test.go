@@ -0,0 +1,62 @@
+package generatorreceiver
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lightstep/telemetry-generator/generatorreceiver/internal/clock"
+	"github.com/lightstep/telemetry-generator/generatorreceiver/internal/flags"
+	"github.com/lightstep/telemetry-generator/generatorreceiver/internal/topology"
+)
+
+// runScheduler ticks the scheduler on a simulated clock for d, starting right away like the
+// running generators do, and returns the number of ticks and traces.
+func runScheduler(rootRoute topology.RootRoute, d time.Duration) (ticks int, traces int) {
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	simulated := clock.NewSimulated(start)
+	defer clock.Set(simulated)()
+
+	s := newRateScheduler(rootRoute, rand.New(rand.NewSource(1)))
+	for now := start; now.Before(start.Add(d)); now = now.Add(s.next()) {
+		simulated.Set(now)
+		arrivals := s.arrivals(now)
+		for _, arrival := range arrivals {
+			if arrival.After(now) || arrival.Before(start) {
+				panic("arrival out of the tick's window")
+			}
+		}
+		ticks++
+		traces += len(arrivals)
+	}
+	return ticks, traces
+}
+
+func TestRateScheduler(t *testing.T) {
+	flags.Manager.Clear()
+	tests := []struct {
+		name      string
+		rootRoute topology.RootRoute
+		duration  time.Duration
+		ticks     int
+		traces    int
+		delta     float64
+	}{
+		{name: "a few a day", rootRoute: topology.RootRoute{TracesPerHour: 3.0 / 24}, duration: 24 * time.Hour, ticks: 3, traces: 3},
+		{name: "one a second", rootRoute: topology.RootRoute{TracesPerHour: 3600}, duration: time.Minute, ticks: 60, traces: 60},
+		// batches are generated on the tick at the end of the window they arrived in, so the last
+		// window of the duration is generated right after it
+		{name: "above the old ticker limit", rootRoute: topology.RootRoute{TracesPerHour: 720000}, duration: time.Second, ticks: 100, traces: 1 + 99*2},
+		{name: "ten thousand a second", rootRoute: topology.RootRoute{TracesPerHour: 36000000}, duration: time.Second, ticks: 100, traces: 1 + 99*100},
+		{name: "poisson", rootRoute: topology.RootRoute{TracesPerHour: 36000000, Arrival: topology.PoissonArrival}, duration: time.Second, ticks: 100, traces: 1 + 99*100, delta: 300},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ticks, traces := runScheduler(tt.rootRoute, tt.duration)
+			require.Equal(t, tt.ticks, ticks)
+			require.InDelta(t, tt.traces, traces, tt.delta)
+		})
+	}
+}
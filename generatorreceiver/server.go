@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net"
 	"net/http"
 
@@ -13,9 +14,10 @@ import (
 )
 
 type httpServer struct {
-	server *http.Server
-	logger *zap.Logger
-	config *Config
+	server   *http.Server
+	logger   *zap.Logger
+	config   *Config
+	receiver *generatorReceiver
 }
 
 type flagHttpResponse struct {
@@ -84,10 +86,105 @@ func (h *httpServer) setFlag(w http.ResponseWriter, r *http.Request) {
 	_, _ = fmt.Fprintf(w, "flag %s updated", f)
 }
 
+func (h *httpServer) topology(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.receiver.mu.Lock()
+		topoBytes := h.receiver.topoBytes
+		h.receiver.mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(topoBytes)
+	case http.MethodPost:
+		topoBytes, err := io.ReadAll(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = fmt.Fprintf(w, "bad request: could not read body: %v", err)
+			return
+		}
+		err = h.receiver.reloadTopo(topoBytes)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = fmt.Fprintf(w, "bad request: %v", err)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = fmt.Fprintf(w, "topology updated")
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		_, _ = fmt.Fprintf(w, "method not allowed")
+	}
+}
+
+// getBatchStats reports how the batches are being consumed, e.g. to tell whether the consumers
+// keep up with the generators.
+func (h *httpServer) getBatchStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		_, _ = fmt.Fprintf(w, "method not allowed")
+		return
+	}
+	h.receiver.mu.Lock()
+	b := h.receiver.batcher
+	h.receiver.mu.Unlock()
+	if b == nil {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = fmt.Fprintf(w, "batching is disabled")
+		return
+	}
+
+	resp, err := json.MarshalIndent(b.snapshot(), "", "  ")
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = fmt.Fprintf(w, "internal error: %v", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, _ = fmt.Fprintf(w, "%s", string(resp))
+}
+
+type validateHttpResponse struct {
+	Valid       bool         `json:"valid"`
+	Diagnostics []Diagnostic `json:"diagnostics"`
+}
+
+// validateTopology lints the posted topology without applying it.
+func (h *httpServer) validateTopology(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		_, _ = fmt.Fprintf(w, "method not allowed")
+		return
+	}
+	topoBytes, err := io.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = fmt.Fprintf(w, "bad request: could not read body: %v", err)
+		return
+	}
+
+	diagnostics := lintTopo(topoBytes)
+
+	if diagnostics == nil {
+		diagnostics = []Diagnostic{}
+	}
+	resp, err := json.MarshalIndent(validateHttpResponse{Valid: !HasErrors(diagnostics), Diagnostics: diagnostics}, "", "  ")
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = fmt.Fprintf(w, "internal error: %v", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, _ = fmt.Fprintf(w, "%s", string(resp))
+}
+
 func (h *httpServer) Start(_ context.Context, host component.Host) error {
 	handler := http.NewServeMux()
 	handler.HandleFunc("/api/v1/flags", h.getFlags)
 	handler.HandleFunc("/api/v1/flag", h.setFlag)
+	handler.HandleFunc("/api/v1/topology", h.topology)
+	handler.HandleFunc("/api/v1/topology/validate", h.validateTopology)
+	handler.HandleFunc("/api/v1/batch", h.getBatchStats)
 
 	var listener net.Listener
 	var err error
@@ -115,10 +212,11 @@ func (h *httpServer) Shutdown(ctx context.Context) error {
 	return h.server.Shutdown(ctx)
 }
 
-func newHTTPServer(config *Config, logger *zap.Logger) (*httpServer, error) {
+func newHTTPServer(config *Config, logger *zap.Logger, receiver *generatorReceiver) (*httpServer, error) {
 	h := &httpServer{
-		config: config,
-		logger: logger,
+		config:   config,
+		logger:   logger,
+		receiver: receiver,
 	}
 
 	return h, nil
@@ -0,0 +1,162 @@
+package generatorreceiver
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/lightstep/telemetry-generator/generatorreceiver/internal/flags"
+)
+
+const telemetryScope = "github.com/lightstep/telemetry-generator/generatorreceiver"
+
+// receiverTelemetry counts what the receiver generates. The counts are reported through the
+// collector's own telemetry while the receiver runs, see registerTelemetry. They count what is
+// generated rather than what the next consumer accepts, which is why the receiverhelper's
+// ObsReport isn't used: with batching, spans and metrics are consumed long after they were
+// generated, and by other goroutines.
+type receiverTelemetry struct {
+	spans         int64
+	logRecords    int64
+	metrics       int64
+	dataPoints    int64
+	consumeErrors int64
+	podRestarts   int64
+
+	// of the last created pipeline, see setTelemetry
+	settings     component.TelemetrySettings
+	id           component.ID
+	registration metric.Registration
+}
+
+func (t *receiverTelemetry) addTraces(td ptrace.Traces) {
+	atomic.AddInt64(&t.spans, int64(td.SpanCount()))
+}
+
+func (t *receiverTelemetry) addLogs(ld plog.Logs) {
+	atomic.AddInt64(&t.logRecords, int64(ld.LogRecordCount()))
+}
+
+func (t *receiverTelemetry) addMetrics(md pmetric.Metrics) {
+	atomic.AddInt64(&t.metrics, int64(md.MetricCount()))
+	atomic.AddInt64(&t.dataPoints, int64(md.DataPointCount()))
+}
+
+func (t *receiverTelemetry) addConsumeError() {
+	atomic.AddInt64(&t.consumeErrors, 1)
+}
+
+func (t *receiverTelemetry) addPodRestart() {
+	atomic.AddInt64(&t.podRestarts, 1)
+}
+
+// setTelemetry keeps the telemetry settings and ID of a created pipeline for the next Start. The
+// receiver is shared by the traces, metrics and logs pipelines, and by the pipelines of the
+// collector's next configuration once these are shut down, so the last created one wins.
+func (g *generatorReceiver) setTelemetry(settings component.TelemetrySettings, id component.ID) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.telemetry.settings, g.telemetry.id = settings, id
+}
+
+// registerTelemetry reports the receiver's counts, the lag of its generators behind their
+// schedule and the number of active flags through the meter provider of the collector, until
+// unregisterTelemetry is called. The meter may hold its own lock while it runs the callback, which
+// locks g.mu, so g.mu must not be held.
+func (g *generatorReceiver) registerTelemetry() error {
+	g.mu.Lock()
+	settings, id := g.telemetry.settings, g.telemetry.id
+	g.mu.Unlock()
+	if settings.MeterProvider == nil {
+		return nil
+	}
+
+	meter := settings.MeterProvider.Meter(telemetryScope)
+	var err error
+	counter := func(name, unit, description string) metric.Int64ObservableCounter {
+		c, cerr := meter.Int64ObservableCounter(name, metric.WithUnit(unit), metric.WithDescription(description))
+		if err == nil {
+			err = cerr
+		}
+		return c
+	}
+	spans := counter("generator_spans", "{spans}", "Number of spans generated.")
+	logRecords := counter("generator_log_records", "{records}", "Number of log records generated.")
+	metrics := counter("generator_metrics", "{metrics}", "Number of metrics generated.")
+	dataPoints := counter("generator_data_points", "{datapoints}", "Number of metric data points generated.")
+	consumeErrors := counter("generator_consume_errors", "{errors}", "Number of times the next consumer failed to consume generated telemetry.")
+	podRestarts := counter("generator_pod_restarts", "{restarts}", "Number of simulated Kubernetes pod restarts.")
+	if err != nil {
+		return err
+	}
+	lag, err := meter.Float64ObservableGauge("generator_schedule_lag", metric.WithUnit("s"),
+		metric.WithDescription("How late the generator that is furthest behind its schedule was on its last tick."))
+	if err != nil {
+		return err
+	}
+	activeFlags, err := meter.Int64ObservableGauge("generator_active_flags", metric.WithUnit("{flags}"),
+		metric.WithDescription("Number of flags currently active."))
+	if err != nil {
+		return err
+	}
+
+	attrs := metric.WithAttributes(attribute.String("receiver", id.String()))
+	registration, err := meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		o.ObserveInt64(spans, atomic.LoadInt64(&g.telemetry.spans), attrs)
+		o.ObserveInt64(logRecords, atomic.LoadInt64(&g.telemetry.logRecords), attrs)
+		o.ObserveInt64(metrics, atomic.LoadInt64(&g.telemetry.metrics), attrs)
+		o.ObserveInt64(dataPoints, atomic.LoadInt64(&g.telemetry.dataPoints), attrs)
+		o.ObserveInt64(consumeErrors, atomic.LoadInt64(&g.telemetry.consumeErrors), attrs)
+		o.ObserveInt64(podRestarts, atomic.LoadInt64(&g.telemetry.podRestarts), attrs)
+		o.ObserveFloat64(lag, g.scheduleLag().Seconds(), attrs)
+		o.ObserveInt64(activeFlags, int64(flags.Manager.ActiveCount()), attrs)
+		return nil
+	}, spans, logRecords, metrics, dataPoints, consumeErrors, podRestarts, lag, activeFlags)
+	if err != nil {
+		return err
+	}
+	g.mu.Lock()
+	g.telemetry.registration = registration
+	g.mu.Unlock()
+	return nil
+}
+
+// unregisterTelemetry stops reporting the receiver's telemetry. Like registerTelemetry, g.mu must
+// not be held.
+func (g *generatorReceiver) unregisterTelemetry() error {
+	g.mu.Lock()
+	registration := g.telemetry.registration
+	g.telemetry.registration = nil
+	g.mu.Unlock()
+	if registration == nil {
+		return nil
+	}
+	return registration.Unregister()
+}
+
+// scheduleLag returns the highest lag of the running generators on their last tick.
+func (g *generatorReceiver) scheduleLag() time.Duration {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	var highest time.Duration
+	for _, r := range g.traceGenerators {
+		if lag := r.getLag(); lag > highest {
+			highest = lag
+		}
+	}
+	for _, generators := range g.metricGenerators {
+		for _, r := range generators {
+			if lag := r.getLag(); lag > highest {
+				highest = lag
+			}
+		}
+	}
+	return highest
+}
@@ -0,0 +1,231 @@
+package generatorreceiver
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/consumer/consumertest"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/noop"
+	"go.uber.org/zap"
+
+	"github.com/lightstep/telemetry-generator/generatorreceiver/internal/flags"
+)
+
+// testMeterProvider keeps the callback registered on its meter until it is unregistered, so that
+// tests can collect what it observes by name.
+type testMeterProvider struct {
+	noop.MeterProvider
+	meter *testMeter
+}
+
+func (p *testMeterProvider) Meter(string, ...metric.MeterOption) metric.Meter {
+	return p.meter
+}
+
+type testMeter struct {
+	noop.Meter
+	callback metric.Callback
+}
+
+type testInt64Counter struct {
+	noop.Int64ObservableCounter
+	name string
+}
+
+type testInt64Gauge struct {
+	noop.Int64ObservableGauge
+	name string
+}
+
+type testFloat64Gauge struct {
+	noop.Float64ObservableGauge
+	name string
+}
+
+func (m *testMeter) Int64ObservableCounter(name string, _ ...metric.Int64ObservableCounterOption) (metric.Int64ObservableCounter, error) {
+	return testInt64Counter{name: name}, nil
+}
+
+func (m *testMeter) Int64ObservableGauge(name string, _ ...metric.Int64ObservableGaugeOption) (metric.Int64ObservableGauge, error) {
+	return testInt64Gauge{name: name}, nil
+}
+
+func (m *testMeter) Float64ObservableGauge(name string, _ ...metric.Float64ObservableGaugeOption) (metric.Float64ObservableGauge, error) {
+	return testFloat64Gauge{name: name}, nil
+}
+
+func (m *testMeter) RegisterCallback(callback metric.Callback, _ ...metric.Observable) (metric.Registration, error) {
+	m.callback = callback
+	return testRegistration{meter: m}, nil
+}
+
+type testRegistration struct {
+	noop.Registration
+	meter *testMeter
+}
+
+func (r testRegistration) Unregister() error {
+	r.meter.callback = nil
+	return nil
+}
+
+type testObserver struct {
+	noop.Observer
+	values    map[string]float64
+	receivers map[string]bool
+}
+
+func (o testObserver) observe(name string, value float64, opts []metric.ObserveOption) {
+	o.values[name] = value
+	attrs := metric.NewObserveConfig(opts).Attributes()
+	receiver, _ := attrs.Value("receiver")
+	o.receivers[receiver.AsString()] = true
+}
+
+func (o testObserver) ObserveInt64(obsrv metric.Int64Observable, value int64, opts ...metric.ObserveOption) {
+	switch i := obsrv.(type) {
+	case testInt64Counter:
+		o.observe(i.name, float64(value), opts)
+	case testInt64Gauge:
+		o.observe(i.name, float64(value), opts)
+	}
+}
+
+func (o testObserver) ObserveFloat64(obsrv metric.Float64Observable, value float64, opts ...metric.ObserveOption) {
+	o.observe(obsrv.(testFloat64Gauge).name, value, opts)
+}
+
+// collect returns the values observed by name, and the receiver IDs they were observed for.
+func (m *testMeter) collect(t *testing.T) (map[string]float64, map[string]bool) {
+	o := testObserver{values: make(map[string]float64), receivers: make(map[string]bool)}
+	require.NoError(t, m.callback(context.Background(), o))
+	return o.values, o.receivers
+}
+
+const telemetryTestTopo = `
+topology:
+  services:
+    frontend:
+      metrics:
+        - name: batch_jobs
+          type: Gauge
+          min: 1
+          max: 10
+      routes:
+        /product:
+          maxLatencyMillis: 100
+      resourceAttrSets:
+        - kubernetes:
+            cluster_name: test-cluster
+            pod_count: 1
+            restart:
+              every: 30m
+flags:
+  - name: frontend_errors
+rootRoutes:
+  - service: frontend
+    route: /product
+    tracesPerHour: 3600
+`
+
+func TestGeneratorReceiver_Telemetry(t *testing.T) {
+	topoPath := filepath.Join(t.TempDir(), "topo.yaml")
+	require.NoError(t, os.WriteFile(topoPath, []byte(telemetryTestTopo), 0600))
+
+	flags.Manager.Clear()
+	g := &generatorReceiver{}
+	g.setup(&Config{Path: topoPath, Backfill: BackfillConfig{Start: "2023-01-01T00:00:00Z", End: "2023-01-01T02:00:00Z"}}, zap.NewNop(), 123)
+	traces := new(consumertest.TracesSink)
+	g.traceConsumer = traces
+	g.metricConsumer = consumertest.NewErr(errors.New("metrics refused"))
+	meter := &testMeter{}
+	settings := componenttest.NewNopTelemetrySettings()
+	settings.MeterProvider = &testMeterProvider{meter: meter}
+	g.setTelemetry(settings, component.NewID("generator"))
+	require.NoError(t, g.Start(context.Background(), componenttest.NewNopHost()))
+	t.Cleanup(func() { require.NoError(t, g.Shutdown(context.Background())) })
+
+	// a trace every second for 2 hours, and the batch_jobs metric along with the Kubernetes
+	// metrics of the pod every 15 seconds, all of which the metrics consumer refuses.
+	require.Eventually(t, func() bool {
+		return traces.SpanCount() == 7200 && atomic.LoadInt64(&g.telemetry.consumeErrors) == 480*int64(len(g.metricGenerators["frontend"]))
+	}, 30*time.Second, 10*time.Millisecond)
+
+	flags.Manager.GetFlag("frontend_errors").Enable()
+	values, receivers := meter.collect(t)
+	require.Equal(t, map[string]bool{"generator": true}, receivers)
+	require.Equal(t, 7200.0, values["generator_spans"])
+	require.Equal(t, 0.0, values["generator_log_records"])
+	require.Equal(t, 480.0*float64(len(g.metricGenerators["frontend"])), values["generator_metrics"])
+	// a single pod, so each metric has a single data point
+	require.Equal(t, values["generator_metrics"], values["generator_data_points"])
+	require.Equal(t, values["generator_metrics"], values["generator_consume_errors"])
+	// the pod restarts every 30 minutes of the backfill, after having started at its beginning
+	require.Equal(t, 3.0, values["generator_pod_restarts"])
+	require.Equal(t, 1.0, values["generator_active_flags"])
+	// backfilled generators are not scheduled
+	require.Equal(t, 0.0, values["generator_schedule_lag"])
+}
+
+func TestGeneratorReceiver_TelemetryRegistration(t *testing.T) {
+	topoPath := filepath.Join(t.TempDir(), "topo.yaml")
+	require.NoError(t, os.WriteFile(topoPath, []byte(telemetryTestTopo), 0600))
+
+	flags.Manager.Clear()
+	g := &generatorReceiver{}
+	g.setup(&Config{Path: topoPath}, zap.NewNop(), 123)
+	g.traceConsumer = new(consumertest.TracesSink)
+	meter := &testMeter{}
+	settings := componenttest.NewNopTelemetrySettings()
+	settings.MeterProvider = &testMeterProvider{meter: meter}
+	g.setTelemetry(settings, component.NewID("generator"))
+	require.NoError(t, g.Start(context.Background(), componenttest.NewNopHost()))
+	require.NotNil(t, meter.callback)
+
+	// the receiver is a singleton, so the callback must not outlive it nor keep its first ID
+	require.NoError(t, g.Shutdown(context.Background()))
+	require.Nil(t, meter.callback)
+
+	g.setTelemetry(settings, component.NewIDWithName("generator", "reloaded"))
+	require.NoError(t, g.Start(context.Background(), componenttest.NewNopHost()))
+	t.Cleanup(func() { require.NoError(t, g.Shutdown(context.Background())) })
+	_, receivers := meter.collect(t)
+	require.Equal(t, map[string]bool{"generator/reloaded": true}, receivers)
+}
+
+func TestGeneratorReceiver_StartMissingTopoFile(t *testing.T) {
+	flags.Manager.Clear()
+	g := &generatorReceiver{}
+	g.setup(&Config{Path: filepath.Join(t.TempDir(), "missing.yaml")}, zap.NewNop(), 123)
+	meter := &testMeter{}
+	settings := componenttest.NewNopTelemetrySettings()
+	settings.MeterProvider = &testMeterProvider{meter: meter}
+	g.setTelemetry(settings, component.NewID("generator"))
+	require.Error(t, g.Start(context.Background(), componenttest.NewNopHost()))
+	require.Nil(t, meter.callback)
+
+	// a failed start must not leave the receiver looking started
+	require.Error(t, g.Start(context.Background(), componenttest.NewNopHost()))
+	require.Nil(t, meter.callback)
+}
+
+func TestRunningGenerator_Lag(t *testing.T) {
+	r := newRunningGenerator(time.Millisecond, func() { time.Sleep(20 * time.Millisecond) })
+	r.interval = func() time.Duration { return time.Millisecond }
+	r.start(1)
+	defer r.stop()
+
+	// each tick takes longer than the interval, so the generator falls further and further behind
+	require.Eventually(t, func() bool {
+		return r.getLag() > 50*time.Millisecond
+	}, 5*time.Second, 10*time.Millisecond)
+}